@@ -0,0 +1,85 @@
+package main
+
+import (
+	"blockchain-backend/service"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// runCommand runs a subcommand instead of starting the server, export and import work on the chain of this node.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "export", "import":
+		n, err := openNode()
+		if err != nil {
+			return err
+		}
+		if args[0] == "export" {
+			return exportCommand(args[1:], n.blockChainSvc)
+		}
+		return importCommand(args[1:], n.blockChainSvc)
+	default:
+		return fmt.Errorf("unknown command %q, use export or import", args[0])
+	}
+}
+
+func exportCommand(args []string, blockChainSvc service.IBlockchainService) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", string(service.NDJSON), "ndjson or binary")
+	from := flags.Int64("from", 1, "first block number")
+	to := flags.Int64("to", 0, "last block number, the head by default")
+	output := flags.String("o", "-", "output file, - for stdout")
+	_ = flags.Parse(args)
+
+	exportFormat, err := service.ParseExportFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return blockChainSvc.ExportChain(w, exportFormat, *from, *to)
+}
+
+func importCommand(args []string, blockChainSvc service.IBlockchainService) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "ndjson or binary, detected from the input by default")
+	input := flags.String("i", "-", "input file, - for stdin")
+	_ = flags.Parse(args)
+
+	var importFormat service.ExportFormat
+	if *format != "" {
+		var err error
+		if importFormat, err = service.ParseExportFormat(*format); err != nil {
+			return err
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	result, err := blockChainSvc.ImportChain(r, importFormat)
+	log.Printf("Imported %d blocks, skipped %d, last valid block %d %s, head %s", result.Imported, result.Skipped, result.LastValidBlock, result.LastValidHash, result.Head)
+	if err != nil {
+		return fmt.Errorf("import stopped after block %d: %w", result.LastValidBlock, err)
+	}
+	return nil
+}
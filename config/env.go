@@ -1,11 +1,65 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/spf13/viper"
+)
 
 type Config struct {
 	Port     string `mapstructure:"PORT"`
 	RedisUrl string `mapstructure:"REDIS_URL"`
-	Rpc      string `mapstructure:"RPC"`
+	// where the chain, pool, difficulty and crawler state live: redis (default), memory, or file for an
+	// append-only log at StoragePath on a single node
+	Storage       string `mapstructure:"STORAGE"`
+	StoragePath   string `mapstructure:"STORAGE_PATH"`
+	Rpc           string `mapstructure:"RPC"`
+	MiningWorkers int    `mapstructure:"MINING_WORKERS"`
+	// one proof-of-work attempt in every MiningTraceSampleRate is streamed to GET /block/mine/trace viewers
+	MiningTraceSampleRate int64 `mapstructure:"MINING_TRACE_SAMPLE_RATE"`
+	// difficulty is recomputed every RetargetInterval blocks so blocks arrive every TargetBlockTime seconds,
+	// a RetargetInterval of 0 leaves the difficulty to POST /block/set-difficulty, received blocks must then carry
+	// at least that difficulty
+	RetargetInterval int64 `mapstructure:"RETARGET_INTERVAL"`
+	TargetBlockTime  int64 `mapstructure:"TARGET_BLOCK_TIME"`
+	// accept blocks hashed with the pre canonical encoding string concatenation
+	AllowLegacyBlocks bool `mapstructure:"ALLOW_LEGACY_BLOCKS"`
+	// limits and ordering used to pick pool transactions for a new block, limits of 0 are unlimited
+	MaxBlockTransactions int    `mapstructure:"MAX_BLOCK_TRANSACTIONS"`
+	MaxBlockBytes        int    `mapstructure:"MAX_BLOCK_BYTES"`
+	TxSelectionStrategy  string `mapstructure:"TX_SELECTION_STRATEGY"`
+	// block reward schedule, the reward halves every HalvingInterval blocks and rewards and faucet grants stop at
+	// MaxSupply (0 is uncapped), the faucet grants of one block create at most FaucetLimit coins
+	InitialReward   int64 `mapstructure:"INITIAL_REWARD"`
+	HalvingInterval int64 `mapstructure:"HALVING_INTERVAL"`
+	MaxSupply       int64 `mapstructure:"MAX_SUPPLY"`
+	FaucetLimit     int64 `mapstructure:"FAUCET_LIMIT"`
+	// background miner, mines every AutoMineInterval seconds and/or once the pool holds more than AutoMinePoolThreshold transactions
+	AutoMineEnabled       bool   `mapstructure:"AUTO_MINE_ENABLED"`
+	AutoMineInterval      int64  `mapstructure:"AUTO_MINE_INTERVAL"`
+	AutoMinePoolThreshold int    `mapstructure:"AUTO_MINE_POOL_THRESHOLD"`
+	AutoMineAddress       string `mapstructure:"AUTO_MINE_ADDRESS"`
+	// consensus engine of the chain, pow, poa or pos, a proof-of-authority chain is signed in turn by the comma
+	// separated POA_SIGNERS addresses and this node signs with PoaSignerKey when it is one of them,
+	// a proof-of-stake node proposes with PosValidatorKey when its stake is drawn
+	Consensus       string `mapstructure:"CONSENSUS"`
+	PoaSigners      string `mapstructure:"POA_SIGNERS"`
+	PoaSignerKey    string `mapstructure:"POA_SIGNER_KEY"`
+	PosValidatorKey string `mapstructure:"POS_VALIDATOR_KEY"`
+	// other nodes this node announces blocks and transactions to, comma separated base URLs, PeerURL is the
+	// base URL of this node they fetch missing blocks from, the best peer is synced every PeerSyncInterval
+	// seconds (0 disables)
+	Peers            string `mapstructure:"PEERS"`
+	PeerURL          string `mapstructure:"PEER_URL"`
+	PeerSyncInterval int64  `mapstructure:"PEER_SYNC_INTERVAL"`
+	// nodes sharing one redis each set their own NodeID, they keep their state under NODE:<NodeID>:, share the
+	// blocks by hash and publish their blocks and transactions to each other, a node without one has the redis
+	// to itself
+	NodeID string `mapstructure:"NODE_ID"`
+	// reset, new genesis block, re-mine, restore and a replace chain that rolls back blocks snapshot the chain and
+	// pool they replace, the last ChainHistorySize snapshots are kept (0 keeps 10)
+	ChainHistorySize int `mapstructure:"CHAIN_HISTORY_SIZE"`
 }
 
 func LoadEnv() (cfg Config, err error) {
@@ -13,8 +67,9 @@ func LoadEnv() (cfg Config, err error) {
 	viper.AutomaticEnv()
 	viper.SetConfigType("")
 
+	// without a .env file every setting keeps its zero value default, as in tests
 	err = viper.ReadInConfig()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		panic(err)
 	}
 
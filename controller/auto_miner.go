@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"blockchain-backend/controller/dto"
+	"blockchain-backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IAutoMinerController interface {
+	SetupRoutes(group *gin.RouterGroup)
+	start() func(c *gin.Context)
+	stop() func(c *gin.Context)
+	status() func(c *gin.Context)
+}
+
+type autoMinerController struct {
+	autoMinerSvc service.IAutoMinerService
+}
+
+func NewAutoMinerController(autoMinerSvc service.IAutoMinerService) IAutoMinerController {
+	return &autoMinerController{
+		autoMinerSvc: autoMinerSvc,
+	}
+}
+
+func (ac *autoMinerController) SetupRoutes(group *gin.RouterGroup) {
+	group.POST("/start", ac.start())
+	group.POST("/stop", ac.stop())
+	group.GET("/status", ac.status())
+}
+
+// @Summary Start auto-miner
+// @Description Mine in the background every interval seconds and/or once the pool holds more than pool_threshold transactions, omitted fields keep the configured value
+// @Tags auto-miner
+// @Accept json
+// @Produce json
+// @Param config body dto.AutoMinerData false "Auto-miner settings"
+// @Success 200
+// @Router /auto-miner/start [post]
+func (ac *autoMinerController) start() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.AutoMinerData
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(400, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+
+		cfg := ac.autoMinerSvc.DefaultConfig()
+		if body.Interval != nil {
+			cfg.Interval = *body.Interval
+		}
+		if body.PoolThreshold != nil {
+			cfg.PoolThreshold = *body.PoolThreshold
+		}
+		if body.MinerAddress != nil {
+			cfg.MinerAddress = *body.MinerAddress
+		}
+
+		if err := ac.autoMinerSvc.Start(cfg); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "auto-miner started",
+			"data":    ac.autoMinerSvc.Status(),
+		})
+	}
+}
+
+// @Summary Stop auto-miner
+// @Description Stop the background miner
+// @Tags auto-miner
+// @Produce json
+// @Success 200
+// @Router /auto-miner/stop [post]
+func (ac *autoMinerController) stop() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if err := ac.autoMinerSvc.Stop(); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "auto-miner stopped",
+			"data":    ac.autoMinerSvc.Status(),
+		})
+	}
+}
+
+// @Summary Auto-miner status
+// @Description Get whether the background miner runs, its settings, the mining jobs it started and what triggered the last one
+// @Tags auto-miner
+// @Produce json
+// @Success 200
+// @Router /auto-miner/status [get]
+func (ac *autoMinerController) status() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": ac.autoMinerSvc.Status(),
+		})
+	}
+}
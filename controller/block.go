@@ -4,7 +4,11 @@ import (
 	"blockchain-backend/controller/dto"
 	"blockchain-backend/service"
 	"blockchain-backend/util"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
+	"io"
 	"strconv"
 )
 
@@ -20,6 +24,30 @@ type IBlockController interface {
 	getDifficulty() func(c *gin.Context)
 	newGenesisBlock() func(c *gin.Context)
 	checkValidChain() func(c *gin.Context)
+	auditChain() func(c *gin.Context)
+	getMiningJobs() func(c *gin.Context)
+	getMiningJob() func(c *gin.Context)
+	cancelMiningJob() func(c *gin.Context)
+	setWorkers() func(c *gin.Context)
+	getMiningStats() func(c *gin.Context)
+	getMerkleProof() func(c *gin.Context)
+	verifyMerkleProof() func(c *gin.Context)
+	setBlockPolicy() func(c *gin.Context)
+	getBlockPolicy() func(c *gin.Context)
+	getReward() func(c *gin.Context)
+	remine() func(c *gin.Context)
+	getValidators() func(c *gin.Context)
+	getMiningTrace() func(c *gin.Context)
+	setTraceSampleRate() func(c *gin.Context)
+	getTips() func(c *gin.Context)
+	getBranch() func(c *gin.Context)
+	getOrphans() func(c *gin.Context)
+	getReorgs() func(c *gin.Context)
+	exportChain() func(c *gin.Context)
+	importChain() func(c *gin.Context)
+	getSnapshots() func(c *gin.Context)
+	diffSnapshot() func(c *gin.Context)
+	restoreSnapshot() func(c *gin.Context)
 }
 
 type blockController struct {
@@ -27,21 +55,46 @@ type blockController struct {
 	blockChainSvc      service.IBlockchainService
 	transactionPoolSvc service.ITransactionPoolService
 	transactionSvc     service.ITransactionService
+	miningJobSvc       service.IMiningJobService
 }
 
-func NewBlockController(blockSvc service.IBlockService, blockChainSvc service.IBlockchainService, transactionPoolSvc service.ITransactionPoolService, transactionSvc service.ITransactionService) IBlockController {
+func NewBlockController(blockSvc service.IBlockService, blockChainSvc service.IBlockchainService, transactionPoolSvc service.ITransactionPoolService, transactionSvc service.ITransactionService, miningJobSvc service.IMiningJobService) IBlockController {
 	return &blockController{
 		blockSvc:           blockSvc,
 		blockChainSvc:      blockChainSvc,
 		transactionPoolSvc: transactionPoolSvc,
 		transactionSvc:     transactionSvc,
+		miningJobSvc:       miningJobSvc,
 	}
 }
 
 func (bc *blockController) SetupRoutes(group *gin.RouterGroup) {
 	group.GET("/", bc.getBlocks())
 	group.GET("/:blockNumber", bc.getBlock())
+	group.GET("/tips", bc.getTips())
+	group.GET("/branch/:hash", bc.getBranch())
+	group.GET("/orphans", bc.getOrphans())
+	group.GET("/reorgs", bc.getReorgs())
+	group.GET("/export", bc.exportChain())
+	group.POST("/import", bc.importChain())
+	group.GET("/history", bc.getSnapshots())
+	group.GET("/history/:snapshotId/diff", bc.diffSnapshot())
+	group.POST("/history/:snapshotId/restore", bc.restoreSnapshot())
+	group.POST("/:blockNumber/remine", bc.remine())
 	group.POST("/mine", bc.mine())
+	group.GET("/mine/jobs", bc.getMiningJobs())
+	group.GET("/mine/jobs/:jobId", bc.getMiningJob())
+	group.POST("/mine/jobs/:jobId/cancel", bc.cancelMiningJob())
+	group.GET("/mine/trace", bc.getMiningTrace())
+	group.POST("/set-trace-sample-rate", bc.setTraceSampleRate())
+	group.POST("/set-workers", bc.setWorkers())
+	group.GET("/mining-stats", bc.getMiningStats())
+	group.POST("/set-block-policy", bc.setBlockPolicy())
+	group.GET("/get-block-policy", bc.getBlockPolicy())
+	group.GET("/reward", bc.getReward())
+	group.GET("/validators", bc.getValidators())
+	group.GET("/merkle-proof/:txHash", bc.getMerkleProof())
+	group.POST("/verify-merkle-proof", bc.verifyMerkleProof())
 	group.POST("/replace-chain", bc.replaceChain())
 	group.POST("/hash", bc.hash()) // use-case 1
 	group.POST("/reset", bc.reset())
@@ -49,26 +102,100 @@ func (bc *blockController) SetupRoutes(group *gin.RouterGroup) {
 	group.GET("/get-difficulty", bc.getDifficulty())
 	group.POST("/new-genesis-block", bc.newGenesisBlock())
 	group.GET("/check-valid-chain", bc.checkValidChain())
+	group.POST("/audit-chain", bc.auditChain())
 }
 
+// @Summary Get difficulty
+// @Description Get the difficulty set on this node, the retarget settings and the difficulty the next block must meet, with its bits and target under proof-of-work
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/get-difficulty [get]
 func (bc *blockController) getDifficulty() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		difficulty := bc.blockSvc.GetDifficulty()
+		interval, targetBlockTime := bc.blockSvc.GetRetargetConfig()
+		blocks := bc.blockChainSvc.GetBlocks().Blocks
+		nextBits := bc.blockSvc.NextBits(blocks)
 
-		c.JSON(200, gin.H{
-			"difficulty": difficulty,
-		})
+		response := gin.H{
+			"consensus":         bc.blockSvc.Engine().Name(),
+			"difficulty":        difficulty,
+			"next_difficulty":   bc.blockSvc.Engine().CalcDifficulty(blocks),
+			"retarget_interval": interval,
+			"target_block_time": targetBlockTime,
+		}
+		if bc.blockSvc.Engine().Name() == service.ProofOfWork {
+			response["next_bits"] = fmt.Sprintf("%08x", nextBits)
+			response["next_target"] = hexutil.EncodeBig(util.CompactToBig(nextBits))
+			response["next_exact_difficulty"] = util.TargetDifficulty(util.CompactToBig(nextBits))
+		}
+		if len(blocks) > 0 {
+			response["chain_work"] = blocks[len(blocks)-1].ChainWork
+		}
+
+		c.JSON(200, response)
 	}
 }
 
+// @Summary Check valid chain
+// @Description Validate the local chain, with audit=true every violation of every block is reported
+// @Tags block
+// @Produce json
+// @Param audit query bool false "Report every violation"
+// @Success 200
+// @Router /block/check-valid-chain [get]
 func (bc *blockController) checkValidChain() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		chain := bc.blockChainSvc.GetBlocks()
-		ok, blockNumber := bc.blockChainSvc.IsValidChain(chain)
+
+		if c.Query("audit") == "true" {
+			c.JSON(200, gin.H{
+				"data": bc.blockChainSvc.AuditChain(chain),
+			})
+			return
+		}
+
+		err := bc.blockChainSvc.ValidateChain(chain)
+
+		var validationErr *service.ChainValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(200, gin.H{
+				"is_valid":     false,
+				"block_number": validationErr.BlockNumber,
+				"error":        validationErr,
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"is_valid":     true,
+			"block_number": len(chain.Blocks),
+		})
+	}
+}
+
+// @Summary Audit chain
+// @Description Report every violation of every block of a chain, such as an imported chain, without replacing the local chain
+// @Tags block
+// @Accept json
+// @Produce json
+// @Param chain body service.Chain true "Chain"
+// @Success 200
+// @Router /block/audit-chain [post]
+func (bc *blockController) auditChain() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		body := service.Chain{}
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 
 		c.JSON(200, gin.H{
-			"is_valid":     ok,
-			"block_number": blockNumber,
+			"data": bc.blockChainSvc.AuditChain(body),
 		})
 	}
 }
@@ -85,7 +212,12 @@ func (bc *blockController) newGenesisBlock() func(c *gin.Context) {
 		}
 
 		genesisBlock := bc.blockSvc.Genesis(body.Nonce, body.Difficulty)
-		bc.blockChainSvc.ReplaceBlock(genesisBlock)
+		if err := bc.blockChainSvc.ReplaceBlock(genesisBlock); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 
 		c.JSON(200, gin.H{
 			"message": "genesis block created successfully",
@@ -121,16 +253,210 @@ func (bc *blockController) setDifficulty() func(c *gin.Context) {
 			return
 		}
 
+		if bc.blockSvc.Engine().Name() != service.ProofOfWork {
+			c.JSON(400, gin.H{
+				"error": "difficulty is only set for proof-of-work, the consensus engine is " + bc.blockSvc.Engine().Name(),
+			})
+			return
+		}
+
 		bc.blockSvc.SetDifficulty(body.Difficulty)
 
+		message := "difficulty set successfully"
+		if interval, _ := bc.blockSvc.GetRetargetConfig(); interval > 0 {
+			message = "difficulty set successfully, retargeting is enabled so it applies from the next genesis block"
+		}
+
 		c.JSON(200, gin.H{
-			"message": "difficulty set successfully",
+			"message": message,
 			"data":    bc.blockSvc.GetDifficulty(),
 		})
 	}
 
 }
 
+// @Summary Set workers
+// @Description Set how many goroutines search the nonce space of the next mining runs
+// @Tags block
+// @Accept json
+// @Produce json
+// @Param workers body dto.SetWorkersData true "Workers"
+// @Success 200
+// @Router /block/set-workers [post]
+func (bc *blockController) setWorkers() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body *dto.SetWorkersData
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := body.Validate(); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		bc.blockSvc.SetWorkers(body.Workers)
+
+		c.JSON(200, gin.H{
+			"message": "workers set successfully",
+			"data":    bc.blockSvc.GetWorkers(),
+		})
+	}
+}
+
+// @Summary Mining trace
+// @Description Stream sampled proof-of-work attempts of every mining run as server-sent events
+// @Tags block
+// @Produce text/event-stream
+// @Success 200
+// @Router /block/mine/trace [get]
+func (bc *blockController) getMiningTrace() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		events, unsubscribe := bc.blockSvc.SubscribeMiningTrace()
+		defer unsubscribe()
+
+		c.SSEvent("subscribed", gin.H{
+			"sample_rate": bc.blockSvc.GetTraceSampleRate(),
+		})
+		c.Writer.Flush()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				c.SSEvent(string(event.Type), event)
+				return true
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
+
+// @Summary Set trace sample rate
+// @Description Stream one proof-of-work attempt in every sample_rate hashes to the mining trace
+// @Tags block
+// @Accept json
+// @Produce json
+// @Param sample_rate body dto.SetTraceSampleRateData true "Sample rate"
+// @Success 200
+// @Router /block/set-trace-sample-rate [post]
+func (bc *blockController) setTraceSampleRate() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body *dto.SetTraceSampleRateData
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := body.Validate(); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		bc.blockSvc.SetTraceSampleRate(body.SampleRate)
+
+		c.JSON(200, gin.H{
+			"message": "trace sample rate set successfully",
+			"data":    bc.blockSvc.GetTraceSampleRate(),
+		})
+	}
+}
+
+// @Summary Mining stats
+// @Description Get the hash rate of the last nonce search and the worker count, mine with workers=1 to compare against single-threaded mining
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/mining-stats [get]
+func (bc *blockController) getMiningStats() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"workers": bc.blockSvc.GetWorkers(),
+			"data":    bc.blockSvc.GetMiningStats(),
+		})
+	}
+}
+
+// @Summary Set block policy
+// @Description Set the limits and the strategy (oldest, fee or fair) used to pick pool transactions for a block, limits of 0 are unlimited
+// @Tags block
+// @Accept json
+// @Produce json
+// @Param policy body dto.BlockPolicyData true "Block policy"
+// @Success 200
+// @Router /block/set-block-policy [post]
+func (bc *blockController) setBlockPolicy() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.BlockPolicyData
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		err := bc.blockChainSvc.SetBlockPolicy(service.BlockPolicy{
+			MaxTransactions: body.MaxTransactions,
+			MaxBytes:        body.MaxBytes,
+			Strategy:        service.TxSelectionStrategy(body.Strategy),
+		})
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "block policy set successfully",
+			"data":    bc.blockChainSvc.GetBlockPolicy(),
+		})
+	}
+}
+
+// @Summary Get block policy
+// @Description Get the limits and the strategy used to pick pool transactions for a block
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/get-block-policy [get]
+func (bc *blockController) getBlockPolicy() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.blockChainSvc.GetBlockPolicy(),
+		})
+	}
+}
+
+// @Summary Get reward
+// @Description Get the reward schedule, the reward of the next block, when it halves next and the coins issued as rewards and faucet grants
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/reward [get]
+func (bc *blockController) getReward() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.blockChainSvc.GetRewardInfo(),
+		})
+	}
+}
+
 func (bc *blockController) reset() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		bc.blockChainSvc.Reset()
@@ -158,6 +484,21 @@ func (bc *blockController) getBlocks() func(c *gin.Context) {
 	}
 }
 
+// @Summary Get validators
+// @Description Get the proof-of-stake stakes and the proposer of the next block
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/validators [get]
+func (bc *blockController) getValidators() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"consensus": bc.blockSvc.Engine().Name(),
+			"data":      bc.blockChainSvc.GetValidators(),
+		})
+	}
+}
+
 func (bc *blockController) getBlock() func(c *gin.Context) {
 	return func(c *gin.Context) {
 
@@ -183,6 +524,164 @@ func (bc *blockController) getBlock() func(c *gin.Context) {
 	}
 }
 
+// @Summary Get tips
+// @Description Get the blocks without children of every branch of the block tree, the heaviest first
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/tips [get]
+func (bc *blockController) getTips() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.blockChainSvc.GetTips(),
+		})
+	}
+}
+
+// @Summary Get branch
+// @Description Get the blocks from the genesis block to the block hash and where they fork off the canonical chain
+// @Tags block
+// @Produce json
+// @Param hash path string true "Block hash"
+// @Success 200
+// @Router /block/branch/{hash} [get]
+func (bc *blockController) getBranch() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		branch, err := bc.blockChainSvc.GetBranch(c.Param("hash"))
+		if err != nil {
+			c.JSON(404, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": branch,
+		})
+	}
+}
+
+// @Summary Get orphans
+// @Description Get the blocks of the block tree that are not on the canonical chain
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/orphans [get]
+func (bc *blockController) getOrphans() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.blockChainSvc.GetOrphans(),
+		})
+	}
+}
+
+// @Summary Get reorgs
+// @Description Get the recent switches of the canonical head that rolled back blocks
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/reorgs [get]
+func (bc *blockController) getReorgs() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.blockChainSvc.GetReorgs(),
+		})
+	}
+}
+
+// @Summary Get chain history
+// @Description Get the snapshots of the chain and pool taken before every reset, new genesis block, re-mine, restore and replace chain that rolls back blocks, newest first
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/history [get]
+func (bc *blockController) getSnapshots() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.blockChainSvc.GetSnapshots(),
+		})
+	}
+}
+
+// @Summary Diff snapshot
+// @Description Compare the blocks and pool transactions of a snapshot with the current chain
+// @Tags block
+// @Produce json
+// @Param snapshotId path int true "Snapshot id"
+// @Success 200
+// @Router /block/history/{snapshotId}/diff [get]
+func (bc *blockController) diffSnapshot() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		snapshotID, err := strconv.ParseInt(c.Param("snapshotId"), 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		diff, err := bc.blockChainSvc.DiffSnapshot(snapshotID)
+		if err != nil {
+			status := 400
+			if errors.Is(err, service.ErrSnapshotNotFound) {
+				status = 404
+			}
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": diff,
+		})
+	}
+}
+
+// @Summary Restore snapshot
+// @Description Bring back the chain and pool of a snapshot, the current chain and pool are snapshotted first
+// @Tags block
+// @Produce json
+// @Param snapshotId path int true "Snapshot id"
+// @Success 200
+// @Router /block/history/{snapshotId}/restore [post]
+func (bc *blockController) restoreSnapshot() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		snapshotID, err := strconv.ParseInt(c.Param("snapshotId"), 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		snapshot, err := bc.blockChainSvc.RestoreSnapshot(snapshotID)
+		if err != nil {
+			status := 400
+			if errors.Is(err, service.ErrSnapshotNotFound) {
+				status = 404
+			}
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "snapshot restored",
+			"data":    snapshot,
+		})
+	}
+}
+
+// @Summary Mine block
+// @Description Start mining a block in the background from the pool transactions, a block_number of 0 mines on top of the tip. The job is polled through /block/mine/jobs/{jobId}
+// @Tags block
+// @Accept json
+// @Produce json
+// @Param block body dto.MineBlockData true "Mine block data"
+// @Success 202
+// @Router /block/mine [post]
 func (bc *blockController) mine() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		body := dto.MineBlockData{}
@@ -194,6 +693,13 @@ func (bc *blockController) mine() func(c *gin.Context) {
 			return
 		}
 
+		if err := body.Validate(); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
 		position := body.BlockNumber
 		if position == 0 {
 			position = -1
@@ -201,10 +707,68 @@ func (bc *blockController) mine() func(c *gin.Context) {
 			position = body.BlockNumber
 		}
 
-		rewardTransaction := bc.transactionSvc.RewardTransaction(body.MinerAddress)
-		bc.transactionPoolSvc.SetTransaction(rewardTransaction)
+		job, err := bc.miningJobSvc.Start(body.Data, body.MinerAddress, position, body.Workers)
+		if err != nil {
+			c.JSON(409, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(202, gin.H{
+			"message": "mining job started",
+			"data":    job,
+		})
+	}
+}
+
+// @Summary Get mining jobs
+// @Description Get the running mining job and the last finished ones, oldest first
+// @Tags block
+// @Produce json
+// @Success 200
+// @Router /block/mine/jobs [get]
+func (bc *blockController) getMiningJobs() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": bc.miningJobSvc.List(),
+		})
+	}
+}
+
+// @Summary Get mining job
+// @Description Get the status and progress of a mining job
+// @Tags block
+// @Produce json
+// @Param jobId path string true "Job id"
+// @Success 200
+// @Router /block/mine/jobs/{jobId} [get]
+func (bc *blockController) getMiningJob() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		job, err := bc.miningJobSvc.Get(c.Param("jobId"))
+		if err != nil {
+			c.JSON(404, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": job,
+		})
+	}
+}
 
-		_, err := bc.blockChainSvc.NewBlock(body.Data, body.MinerAddress, position)
+// @Summary Cancel mining job
+// @Description Stop a running mining job, nothing is added to the chain
+// @Tags block
+// @Produce json
+// @Param jobId path string true "Job id"
+// @Success 200
+// @Router /block/mine/jobs/{jobId}/cancel [post]
+func (bc *blockController) cancelMiningJob() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		job, err := bc.miningJobSvc.Cancel(c.Param("jobId"))
 		if err != nil {
 			c.JSON(400, gin.H{
 				"error": err.Error(),
@@ -213,8 +777,8 @@ func (bc *blockController) mine() func(c *gin.Context) {
 		}
 
 		c.JSON(200, gin.H{
-			"message": "mined block successfully",
-			"data":    bc.blockChainSvc.GetBlocks(),
+			"message": "mining job cancelled",
+			"data":    job,
 		})
 	}
 }
@@ -230,23 +794,116 @@ func (bc *blockController) replaceChain() func(c *gin.Context) {
 			return
 		}
 
-		if ok, _ := bc.blockChainSvc.IsValidChain(body); ok {
-			err := bc.blockChainSvc.ReplaceChain(body)
-			if err != nil {
+		comparison, err := bc.blockChainSvc.ReplaceChain(body)
+		var validationErr *service.ChainValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(400, gin.H{
+				"error":            err.Error(),
+				"validation_error": validationErr,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+				"data":  comparison,
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "chain replaced successfully",
+			"data":    comparison,
+		})
+	}
+}
+
+// @Summary Export chain
+// @Description Stream the canonical chain as NDJSON, one block per line, or in the compact binary format
+// @Tags block
+// @Produce application/x-ndjson,application/octet-stream
+// @Param format query string false "ndjson or binary"
+// @Param from query int false "First block number"
+// @Param to query int false "Last block number, the head by default"
+// @Success 200
+// @Router /block/export [get]
+func (bc *blockController) exportChain() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		format, err := service.ParseExportFormat(c.Query("format"))
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var blockRange [2]int64
+		for i, name := range []string{"from", "to"} {
+			if value := c.Query(name); value != "" {
+				if blockRange[i], err = strconv.ParseInt(value, 10, 64); err != nil {
+					c.JSON(400, gin.H{
+						"error": fmt.Sprintf("%s must be a block number", name),
+					})
+					return
+				}
+			}
+		}
+
+		contentType := "application/x-ndjson"
+		if format == service.BinaryFormat {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=chain.%s", format))
+
+		err = bc.blockChainSvc.ExportChain(c.Writer, format, blockRange[0], blockRange[1])
+		if err != nil && !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// @Summary Import chain
+// @Description Validate an NDJSON or binary chain export block by block and add the valid blocks, a failed import keeps the blocks before the failure and resumes after them when it is sent again
+// @Tags block
+// @Accept application/x-ndjson,application/octet-stream
+// @Produce json
+// @Param format query string false "ndjson or binary, detected from the body by default"
+// @Success 200
+// @Router /block/import [post]
+func (bc *blockController) importChain() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var format service.ExportFormat
+		if c.Query("format") != "" {
+			var err error
+			if format, err = service.ParseExportFormat(c.Query("format")); err != nil {
 				c.JSON(400, gin.H{
 					"error": err.Error(),
 				})
 				return
 			}
-			bc.transactionPoolSvc.Clear()
-			c.JSON(200, gin.H{
-				"message": "chain replaced successfully",
+		}
+
+		result, err := bc.blockChainSvc.ImportChain(c.Request.Body, format)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+				"data":  result,
 			})
 			return
 		}
 
-		c.JSON(400, gin.H{
-			"error": "invalid chain",
+		c.JSON(200, gin.H{
+			"message": "chain imported successfully",
+			"data":    result,
 		})
 	}
 }
@@ -276,3 +933,121 @@ func (bc *blockController) hash() func(c *gin.Context) {
 		})
 	}
 }
+
+// @Summary Get Merkle proof
+// @Description Get the proof that a mined transaction is part of the Merkle root of its block
+// @Tags block
+// @Produce json
+// @Param txHash path string true "Transaction hash"
+// @Success 200
+// @Router /block/merkle-proof/{txHash} [get]
+func (bc *blockController) getMerkleProof() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		proof, err := bc.blockChainSvc.GetMerkleProof(c.Param("txHash"))
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": proof,
+		})
+	}
+}
+
+// @Summary Verify Merkle proof
+// @Description Check a Merkle proof of a transaction hash against a Merkle root
+// @Tags block
+// @Accept json
+// @Produce json
+// @Param proof body dto.VerifyMerkleProofData true "Proof"
+// @Success 200
+// @Router /block/verify-merkle-proof [post]
+func (bc *blockController) verifyMerkleProof() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.VerifyMerkleProofData
+
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		ok := util.VerifyMerkleProof(body.TxHash, body.Proof, body.MerkleRoot)
+
+		c.JSON(200, gin.H{
+			"data": ok,
+		})
+	}
+}
+
+// @Summary Re-mine from a block
+// @Description Replace the data of a block and re-mine it and every block after it, progress is streamed as server-sent events. The re-mined chain replaces the block tree, the old chain is snapshotted
+// @Tags block
+// @Accept json
+// @Produce text/event-stream
+// @Param blockNumber path int true "Block number"
+// @Param data body dto.RemineBlockData true "Remine block data"
+// @Success 200
+// @Router /block/{blockNumber}/remine [post]
+func (bc *blockController) remine() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		blockNumber, err := strconv.ParseInt(c.Param("blockNumber"), 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var body *dto.RemineBlockData
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := body.Validate(); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if _, err := bc.blockChainSvc.GetBlock(blockNumber); err != nil {
+			c.JSON(404, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		// re-mining stops when the client disconnects
+		events := make(chan service.RemineEvent)
+		errs := make(chan error, 1)
+		go func() {
+			defer close(events)
+			errs <- bc.blockChainSvc.RemineFrom(c.Request.Context(), blockNumber, body.Data, body.Workers, func(event service.RemineEvent) {
+				select {
+				case events <- event:
+				case <-c.Request.Context().Done():
+				}
+			})
+		}()
+
+		c.Stream(func(w io.Writer) bool {
+			event, ok := <-events
+			if !ok {
+				if err := <-errs; err != nil {
+					c.SSEvent("error", gin.H{"error": err.Error()})
+				}
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package dto
+
+// AutoMinerData overrides the configured auto-miner settings, omitted fields keep the configured value.
+type AutoMinerData struct {
+	Interval      *int64  `json:"interval"`
+	PoolThreshold *int    `json:"pool_threshold"`
+	MinerAddress  *string `json:"miner_address"`
+}
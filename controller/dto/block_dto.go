@@ -2,6 +2,7 @@ package dto
 
 import (
 	"blockchain-backend/service"
+	"blockchain-backend/util"
 	"fmt"
 )
 
@@ -9,6 +10,12 @@ type MineBlockData struct {
 	MinerAddress string `json:"miner_address" binding:"required"`
 	Data         string `json:"data"`
 	BlockNumber  int64  `json:"block_number"`
+	Workers      int    `json:"workers"`
+}
+
+type RemineBlockData struct {
+	Data    string `json:"data"`
+	Workers int    `json:"workers"`
 }
 
 type HashData struct {
@@ -20,6 +27,26 @@ type SetDifficultyData struct {
 	Difficulty int64 `json:"difficulty" binding:"required"`
 }
 
+type SetWorkersData struct {
+	Workers int `json:"workers" binding:"required"`
+}
+
+type SetTraceSampleRateData struct {
+	SampleRate int64 `json:"sample_rate" binding:"required"`
+}
+
+type VerifyMerkleProofData struct {
+	TxHash     string                 `json:"tx_hash" binding:"required"`
+	MerkleRoot string                 `json:"merkle_root" binding:"required"`
+	Proof      []util.MerkleProofStep `json:"proof"`
+}
+
+type BlockPolicyData struct {
+	MaxTransactions int    `json:"max_transactions"`
+	MaxBytes        int    `json:"max_bytes"`
+	Strategy        string `json:"strategy" binding:"required"`
+}
+
 type GenesisBlockData struct {
 	Nonce      int64 `json:"nonce" binding:"required"`
 	Difficulty int64 `json:"difficulty" binding:"required"`
@@ -50,5 +77,38 @@ func (s *SetDifficultyData) Validate() error {
 	if s.Difficulty <= 0 {
 		return fmt.Errorf("difficulty must be greater than 0")
 	}
+	if s.Difficulty > 255 {
+		return fmt.Errorf("difficulty must be at most 255")
+	}
+	return nil
+}
+
+func (m *MineBlockData) Validate() error {
+	// validate workers must not be negative, 0 uses the configured default
+	if m.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
+	return nil
+}
+
+func (r *RemineBlockData) Validate() error {
+	if r.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
+	return nil
+}
+
+func (s *SetTraceSampleRateData) Validate() error {
+	if s.SampleRate <= 0 {
+		return fmt.Errorf("sample rate must be greater than 0")
+	}
+	return nil
+}
+
+func (s *SetWorkersData) Validate() error {
+	// validate workers must be greater than 0
+	if s.Workers <= 0 {
+		return fmt.Errorf("workers must be greater than 0")
+	}
 	return nil
 }
@@ -0,0 +1,18 @@
+package dto
+
+import "blockchain-backend/service"
+
+type PeerData struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// AnnounceBlockData is a block another node mined or accepted, From is the base URL of that node.
+type AnnounceBlockData struct {
+	Block service.Block `json:"block" binding:"required"`
+	From  string        `json:"from"`
+}
+
+type AnnounceTransactionData struct {
+	Transaction service.Transaction `json:"transaction" binding:"required"`
+	From        string              `json:"from"`
+}
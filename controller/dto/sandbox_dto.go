@@ -0,0 +1,6 @@
+package dto
+
+type CreateSandboxData struct {
+	ID          string `json:"id" binding:"required"`
+	Description string `json:"description"`
+}
@@ -1,20 +1,27 @@
 package dto
 
-import "fmt"
+import (
+	"blockchain-backend/service"
+	"fmt"
+)
 
 type SignTransactionRequest struct {
 	PrivateKey string `json:"private_key" binding:"required"`
+	Type       string `json:"type"`
 	From       string `json:"from" binding:"required"`
 	To         string `json:"to" binding:"required"`
 	Value      int64  `json:"value" binding:"required"`
+	Fee        int64  `json:"fee"`
 	Data       string `json:"data" binding:"required"`
 	Timestamp  int64  `json:"timestamp" binding:"required,timestampInSeconds"`
 }
 
 type CreateTransactionRequest struct {
+	Type      string `json:"type"`
 	From      string `json:"from" binding:"required"`
 	To        string `json:"to" binding:"required"`
 	Value     int64  `json:"value" binding:"required"`
+	Fee       int64  `json:"fee"`
 	Data      string `json:"data" binding:"required"`
 	Timestamp int64  `json:"timestamp" binding:"required,timestampInSeconds"`
 	Signature string `json:"signature" binding:"required"`
@@ -36,8 +43,35 @@ func (c *CreateTransactionRequest) Validate() error {
 		return fmt.Errorf("value must be greater than 0")
 	}
 
-	if c.From == c.To {
-		return fmt.Errorf("from and to must be different")
+	if c.Fee < 0 {
+		return fmt.Errorf("fee must not be negative")
+	}
+
+	return validateTransactionType(c.Type, c.From, c.To)
+}
+
+func (s *SignTransactionRequest) Validate() error {
+	if s.Fee < 0 {
+		return fmt.Errorf("fee must not be negative")
+	}
+
+	return validateTransactionType(s.Type, s.From, s.To)
+}
+
+// validateTransactionType checks the type is empty (a transfer), stake or unstake. Transfers go to another
+// address while stake is locked and unlocked on the sender itself.
+func validateTransactionType(txType, from, to string) error {
+	switch service.TransactionType(txType) {
+	case service.TransferTransaction:
+		if from == to {
+			return fmt.Errorf("from and to must be different")
+		}
+	case service.StakeTransaction, service.UnstakeTransaction:
+		if from != to {
+			return fmt.Errorf("from and to must be the same address for %s transactions", txType)
+		}
+	default:
+		return fmt.Errorf("type must be empty, %s or %s", service.StakeTransaction, service.UnstakeTransaction)
 	}
 
 	return nil
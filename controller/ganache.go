@@ -1,7 +1,7 @@
 package controller
 
 import (
-	"blockchain-backend/infras/redis"
+	"blockchain-backend/infras/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,10 +12,13 @@ type IGanacheController interface {
 }
 
 type ganacheController struct {
+	store storage.IStorage
 }
 
-func NewGanacheController() IGanacheController {
-	return &ganacheController{}
+func NewGanacheController(store storage.IStorage) IGanacheController {
+	return &ganacheController{
+		store: store,
+	}
 }
 
 func (gc *ganacheController) SetupRoutes(group *gin.RouterGroup) {
@@ -34,7 +37,7 @@ func (gc *ganacheController) GetHistory() func(c *gin.Context) {
 			return
 		}
 
-		transactions := redis.RedisService.GetSet(redis.HistoryTransactionsKey + address)
+		transactions := gc.store.GetSet(storage.HistoryTransactionsKey + address)
 
 		c.JSON(200, gin.H{
 			"message": "Get history",
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"blockchain-backend/controller/dto"
+	"blockchain-backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IPeerController interface {
+	SetupRoutes(group *gin.RouterGroup)
+	getPeers() func(c *gin.Context)
+	addPeer() func(c *gin.Context)
+	removePeer() func(c *gin.Context)
+	status() func(c *gin.Context)
+	getBlock() func(c *gin.Context)
+	announceBlock() func(c *gin.Context)
+	announceTransaction() func(c *gin.Context)
+	sync() func(c *gin.Context)
+}
+
+type peerController struct {
+	peerSvc       service.IPeerService
+	blockChainSvc service.IBlockchainService
+}
+
+func NewPeerController(peerSvc service.IPeerService, blockChainSvc service.IBlockchainService) IPeerController {
+	return &peerController{
+		peerSvc:       peerSvc,
+		blockChainSvc: blockChainSvc,
+	}
+}
+
+func (pc *peerController) SetupRoutes(group *gin.RouterGroup) {
+	group.GET("/", pc.getPeers())
+	group.POST("/", pc.addPeer())
+	group.POST("/remove", pc.removePeer())
+	group.GET("/status", pc.status())
+	group.GET("/blocks/:hash", pc.getBlock())
+	group.POST("/blocks", pc.announceBlock())
+	group.POST("/transactions", pc.announceTransaction())
+	group.POST("/sync", pc.sync())
+}
+
+// @Summary Get peers
+// @Description Get the registered peers with the chain they reported last
+// @Tags peer
+// @Produce json
+// @Success 200
+// @Router /peer/ [get]
+func (pc *peerController) getPeers() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": pc.peerSvc.GetPeers(),
+		})
+	}
+}
+
+// @Summary Add peer
+// @Description Register another node by its base URL, it gets every block and pool transaction this node sees
+// @Tags peer
+// @Accept json
+// @Produce json
+// @Param peer body dto.PeerData true "Peer"
+// @Success 200
+// @Router /peer/ [post]
+func (pc *peerController) addPeer() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.PeerData
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		peer, err := pc.peerSvc.AddPeer(body.URL)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "peer added",
+			"data":    peer,
+		})
+	}
+}
+
+// @Summary Remove peer
+// @Tags peer
+// @Accept json
+// @Produce json
+// @Param peer body dto.PeerData true "Peer"
+// @Success 200
+// @Router /peer/remove [post]
+func (pc *peerController) removePeer() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.PeerData
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := pc.peerSvc.RemovePeer(body.URL); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "peer removed",
+		})
+	}
+}
+
+// @Summary Node status
+// @Description Get the height, head and total work of the canonical chain of this node
+// @Tags peer
+// @Produce json
+// @Success 200
+// @Router /peer/status [get]
+func (pc *peerController) status() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": pc.peerSvc.Status(),
+		})
+	}
+}
+
+// @Summary Get block by hash
+// @Description Get any block of the block tree by its hash, side branches included
+// @Tags peer
+// @Produce json
+// @Param hash path string true "Block hash"
+// @Success 200
+// @Router /peer/blocks/{hash} [get]
+func (pc *peerController) getBlock() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		block, err := pc.blockChainSvc.GetBlockByHash(c.Param("hash"))
+		if err != nil {
+			c.JSON(404, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": block,
+		})
+	}
+}
+
+// @Summary Announce block
+// @Description Receive a block from another node, missing ancestors are fetched from the node at from when it is a registered peer
+// @Tags peer
+// @Accept json
+// @Produce json
+// @Param block body dto.AnnounceBlockData true "Block"
+// @Success 200
+// @Router /peer/blocks [post]
+func (pc *peerController) announceBlock() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.AnnounceBlockData
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := pc.peerSvc.ReceiveBlock(body.Block, body.From); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "block received",
+		})
+	}
+}
+
+// @Summary Announce transaction
+// @Description Receive a pool transaction from another node
+// @Tags peer
+// @Accept json
+// @Produce json
+// @Param transaction body dto.AnnounceTransactionData true "Transaction"
+// @Success 200
+// @Router /peer/transactions [post]
+func (pc *peerController) announceTransaction() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.AnnounceTransactionData
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := pc.peerSvc.ReceiveTransaction(body.Transaction); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "transaction received",
+		})
+	}
+}
+
+// @Summary Sync with peers
+// @Description Replace the local chain with the chain of the peer with the most work, or of the given peer, which must be registered first
+// @Tags peer
+// @Accept json
+// @Produce json
+// @Param peer body dto.PeerData false "Peer"
+// @Success 200
+// @Router /peer/sync [post]
+func (pc *peerController) sync() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.PeerData
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(400, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+
+		var result service.SyncResult
+		var err error
+		if body.URL != "" {
+			result, err = pc.peerSvc.SyncWith(body.URL)
+		} else {
+			result, err = pc.peerSvc.Sync()
+		}
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+				"data":  result,
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": result,
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"blockchain-backend/controller/dto"
+	"blockchain-backend/service"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ISandboxController interface {
+	SetupRoutes(group *gin.RouterGroup)
+	getSandboxes() func(c *gin.Context)
+	createSandbox() func(c *gin.Context)
+	getSandbox() func(c *gin.Context)
+	deleteSandbox() func(c *gin.Context)
+}
+
+type sandboxController struct {
+	sandboxSvc service.ISandboxService
+}
+
+func NewSandboxController(sandboxSvc service.ISandboxService) ISandboxController {
+	return &sandboxController{
+		sandboxSvc: sandboxSvc,
+	}
+}
+
+func (sc *sandboxController) SetupRoutes(group *gin.RouterGroup) {
+	group.GET("/", sc.getSandboxes())
+	group.POST("/", sc.createSandbox())
+	group.GET("/:id", sc.getSandbox())
+	group.DELETE("/:id", sc.deleteSandbox())
+}
+
+// @Summary Get sandboxes
+// @Tags sandbox
+// @Produce json
+// @Success 200
+// @Router /sandbox/ [get]
+func (sc *sandboxController) getSandboxes() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"data": sc.sandboxSvc.GetSandboxes(),
+		})
+	}
+}
+
+// @Summary Create sandbox
+// @Description Create a chain of its own, every route of the node is served for it under /sandbox/{id}
+// @Tags sandbox
+// @Accept json
+// @Produce json
+// @Param sandbox body dto.CreateSandboxData true "Sandbox"
+// @Success 200
+// @Router /sandbox/ [post]
+func (sc *sandboxController) createSandbox() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body dto.CreateSandboxData
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		sandbox, err := sc.sandboxSvc.CreateSandbox(body.ID, body.Description)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "sandbox created",
+			"data":    sandbox,
+		})
+	}
+}
+
+// @Summary Get sandbox
+// @Tags sandbox
+// @Produce json
+// @Param id path string true "Sandbox id"
+// @Success 200
+// @Router /sandbox/{id} [get]
+func (sc *sandboxController) getSandbox() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		sandbox, err := sc.sandboxSvc.GetSandbox(c.Param("id"))
+		if err != nil {
+			c.JSON(404, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"data": sandbox,
+		})
+	}
+}
+
+// @Summary Delete sandbox
+// @Description Stop the sandbox and delete its chain, pool and difficulty
+// @Tags sandbox
+// @Produce json
+// @Param id path string true "Sandbox id"
+// @Success 200
+// @Router /sandbox/{id} [delete]
+func (sc *sandboxController) deleteSandbox() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if err := sc.sandboxSvc.DeleteSandbox(c.Param("id")); err != nil {
+			status := 400
+			if errors.Is(err, service.ErrSandboxNotFound) {
+				status = 404
+			}
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "sandbox deleted",
+		})
+	}
+}
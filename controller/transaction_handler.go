@@ -4,9 +4,9 @@ import (
 	"blockchain-backend/controller/dto"
 	"blockchain-backend/service"
 	"blockchain-backend/util"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
-	"strconv"
 )
 
 type ITransactionController interface {
@@ -120,14 +120,36 @@ func (tc *transactionController) signTransaction() func(c *gin.Context) {
 			return
 		}
 
-		if body.Value > tc.walletSvc.CalculateBalance(body.From) {
+		if err := body.Validate(); err != nil {
 			c.JSON(400, gin.H{
-				"error": "Số dư không đủ, vui lòng nhập thấp hơn " + strconv.FormatInt(tc.walletSvc.CalculateBalance(body.From), 10),
+				"error": err.Error(),
 			})
 			return
 		}
 
-		data := util.CryptoHash([]byte(body.From + body.To + strconv.FormatInt(body.Value, 10) + body.Data + strconv.FormatInt(body.Timestamp, 10))).Bytes()
+		if err := tc.checkFunds(service.TransactionType(body.Type), body.From, body.Value, body.Fee); err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		transaction := &service.Transaction{
+			Type:      service.TransactionType(body.Type),
+			From:      body.From,
+			To:        body.To,
+			Value:     body.Value,
+			Fee:       body.Fee,
+			Data:      body.Data,
+			Timestamp: body.Timestamp,
+		}
+		data, err := hexutil.Decode(tc.transactionSvc.TxHash(transaction))
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 
 		signature, err := util.Sign(data, body.PrivateKey)
 		if err != nil {
@@ -143,6 +165,25 @@ func (tc *transactionController) signTransaction() func(c *gin.Context) {
 	}
 }
 
+// checkFunds checks the sender can pay value plus fee, an unstake only pays the fee and can unlock at most the stake.
+func (tc *transactionController) checkFunds(txType service.TransactionType, from string, value, fee int64) error {
+	balance := tc.walletSvc.CalculateBalance(from)
+
+	if txType == service.UnstakeTransaction {
+		if stake := tc.walletSvc.CalculateStake(from); value > stake {
+			return fmt.Errorf("unstake value is more than the stake of %d", stake)
+		}
+		value = 0
+	}
+
+	// value+fee can overflow, so the fee is taken from the balance first
+	if fee > balance || value > balance-fee {
+		return fmt.Errorf("Số dư không đủ, vui lòng nhập thấp hơn %d", balance)
+	}
+
+	return nil
+}
+
 func (tc *transactionController) createTransaction() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var body dto.CreateTransactionRequest
@@ -160,7 +201,16 @@ func (tc *transactionController) createTransaction() func(c *gin.Context) {
 			return
 		}
 
-		transaction, err := tc.transactionSvc.CreateTransaction(body.From, body.To, body.Value, body.Data, body.Timestamp, body.Signature, body.PublicKey)
+		if service.TransactionType(body.Type) == service.UnstakeTransaction {
+			if err := tc.checkFunds(service.UnstakeTransaction, body.From, body.Value, body.Fee); err != nil {
+				c.JSON(400, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+
+		transaction, err := tc.transactionSvc.CreateTransaction(service.TransactionType(body.Type), body.From, body.To, body.Value, body.Fee, body.Data, body.Timestamp, body.Signature, body.PublicKey)
 		if err != nil {
 			c.JSON(400, gin.H{
 				"error": err.Error(),
@@ -4,7 +4,9 @@ import (
 	"blockchain-backend/controller/dto"
 	"blockchain-backend/service"
 	"blockchain-backend/util"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
 	"strconv"
 	"time"
@@ -20,12 +22,14 @@ type IWalletController interface {
 
 type walletController struct {
 	walletSvc          service.IWalletService
+	transactionSvc     service.ITransactionService
 	transactionPoolSvc service.ITransactionPoolService
 }
 
-func NewWalletController(walletService service.IWalletService, transactionPoolSvc service.ITransactionPoolService) IWalletController {
+func NewWalletController(walletService service.IWalletService, transactionSvc service.ITransactionService, transactionPoolSvc service.ITransactionPoolService) IWalletController {
 	return &walletController{
 		walletSvc:          walletService,
+		transactionSvc:     transactionSvc,
 		transactionPoolSvc: transactionPoolSvc,
 	}
 }
@@ -98,6 +102,14 @@ func (wc *walletController) generateKeyPair() func(c *gin.Context) {
 			balanceValue = 1000
 		}
 
+		// blocks only carry faucet grants within the faucet limit
+		if limit := service.NewRewardSchedule().FaucetLimit; balanceValue <= 0 || balanceValue > limit {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("initBalance must be between 1 and %d", limit),
+			})
+			return
+		}
+
 		keyPair, err := wc.walletSvc.GenerateKeyPair(body.SeedPhrase)
 		if err != nil {
 			c.JSON(500, gin.H{
@@ -106,9 +118,6 @@ func (wc *walletController) generateKeyPair() func(c *gin.Context) {
 			return
 		}
 
-		data := util.CryptoHash([]byte(common.Address{}.Hex() + keyPair.Address + strconv.FormatInt(balanceValue, 10) + "" + strconv.FormatInt(time.Now().Unix(), 10))).Bytes()
-		signature, _ := util.Sign(data, keyPair.PrivateKey)
-
 		// add transaction send 1000 to keyPair.Address
 		transaction := &service.Transaction{
 			From:      common.Address{}.Hex(),
@@ -116,10 +125,11 @@ func (wc *walletController) generateKeyPair() func(c *gin.Context) {
 			Value:     balanceValue,
 			Data:      "",
 			Timestamp: time.Now().Unix(),
-			Signature: signature,
 		}
 
-		transaction.Hash = util.CryptoHash([]byte(transaction.From + transaction.To + strconv.FormatInt(transaction.Value, 10) + transaction.Data + strconv.FormatInt(transaction.Timestamp, 10))).Hex()
+		transaction.Hash = wc.transactionSvc.TxHash(transaction)
+		data, _ := hexutil.Decode(transaction.Hash)
+		transaction.Signature, _ = util.Sign(data, keyPair.PrivateKey)
 		wc.transactionPoolSvc.SetTransaction(transaction)
 		c.JSON(200, gin.H{
 			"data": keyPair,
@@ -139,7 +149,8 @@ func (wc *walletController) getBalance() func(c *gin.Context) {
 
 		balance := wc.walletSvc.CalculateBalance(address)
 		c.JSON(200, gin.H{
-			"data": balance,
+			"data":   balance,
+			"staked": wc.walletSvc.CalculateStake(address),
 		})
 	}
 }
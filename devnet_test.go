@@ -0,0 +1,210 @@
+package main
+
+import (
+	"blockchain-backend/infras/storage"
+	"blockchain-backend/service"
+	"blockchain-backend/util"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+)
+
+const devnetTimeout = 15 * time.Second
+
+// devnetNode is a node of the devnet served by an httptest server, it mines to its own wallet.
+type devnetNode struct {
+	*node
+	url    string
+	wallet util.KeyPair
+}
+
+// TestDevnet runs several nodes with memory storage and their own HTTP servers and checks that they converge on
+// one chain: blocks mined on one node reach the others, transactions spread through the pools, competing blocks
+// are resolved by the next block and a node that joins late catches up by syncing.
+func TestDevnet(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+
+	nodes := make([]*devnetNode, 4)
+	for i := range nodes {
+		nodes[i] = startDevnetNode(t)
+	}
+
+	// the nodes before the late one form a line, so blocks and transactions are relayed to reach the far end
+	online, late := nodes[:len(nodes)-1], nodes[len(nodes)-1]
+	for i := 0; i+1 < len(online); i++ {
+		connect(t, online[i], online[i+1])
+	}
+
+	for i := 0; i < 4; i++ {
+		mine(t, online[i%len(online)], "devnet")
+		waitConverged(t, online)
+	}
+
+	// the first node mined block 2, its wallet has funds
+	sender := online[0]
+	transaction := service.Transaction{
+		Type:      service.TransferTransaction,
+		From:      sender.wallet.Address,
+		To:        "0x000000000000000000000000000000000000dEaD",
+		Value:     1,
+		Fee:       1,
+		Data:      "devnet transfer",
+		Timestamp: time.Now().Unix(),
+	}
+	transaction.Hash = sender.transactionSvc.TxHash(&transaction)
+	hash, _ := hexutil.Decode(transaction.Hash)
+	signature, err := util.Sign(hash, sender.wallet.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transaction.Signature = signature
+	sender.transactionPoolSvc.SetTransaction(&transaction)
+	waitFor(t, "transaction to reach every pool", func() bool {
+		for _, n := range online {
+			if _, ok := n.transactionPoolSvc.GetTransactionPool()[transaction.Hash]; !ok {
+				return false
+			}
+		}
+		return true
+	})
+
+	// both ends of the line mine on the same parent to their own wallets, the nodes hold both blocks until the
+	// next block makes one branch heavier
+	first, last := online[0], online[len(online)-1]
+	errs := make(chan error, 2)
+	blocks := make([]*service.Block, 2)
+	for i, miner := range []*devnetNode{first, last} {
+		go func(i int, miner *devnetNode) {
+			block, err := miner.blockChainSvc.NewBlock(context.Background(), "competing", miner.wallet.Address, -1, service.MiningOptions{})
+			blocks[i] = block
+			errs <- err
+		}(i, miner)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if blocks[0].Hash == blocks[1].Hash {
+		t.Fatalf("competing blocks are the same block %s", blocks[0].Hash)
+	}
+	if blocks[0].ParentHash != blocks[1].ParentHash {
+		t.Fatalf("competing blocks have different parents %s and %s", blocks[0].ParentHash, blocks[1].ParentHash)
+	}
+	waitFor(t, "competing blocks to spread", func() bool {
+		return allHave(online, blocks[0].Hash, blocks[1].Hash)
+	})
+
+	tieBreaker := mine(t, last, "tie breaker")
+	waitConverged(t, online)
+	if head := first.peerSvc.Status().Head; head != tieBreaker.Hash {
+		t.Fatalf("node 0 head is %s, want the tie breaker %s", head, tieBreaker.Hash)
+	}
+	if tieBreaker.ParentHash != blocks[1].Hash {
+		t.Fatalf("tie breaker extends %s, want the block of node %d %s", tieBreaker.ParentHash, len(online)-1, blocks[1].Hash)
+	}
+
+	connect(t, late, online[0])
+	result, err := late.peerSvc.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Synced {
+		t.Fatal("late node found no peer with more work")
+	}
+	waitConverged(t, nodes)
+}
+
+func startDevnetNode(t *testing.T) *devnetNode {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(nil)
+	peerURL := "http://" + server.Listener.Addr().String()
+
+	n, err := newNode(storage.NewMemoryStorage(), peerURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.blockChainSvc.Reset()
+
+	engine, err := n.router()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = engine
+	server.Start()
+	t.Cleanup(server.Close)
+
+	wallet, err := util.GenerateKeyPair("blab devnet " + peerURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &devnetNode{node: n, url: peerURL, wallet: wallet}
+}
+
+// connect makes a and b peers of each other.
+func connect(t *testing.T, a, b *devnetNode) {
+	t.Helper()
+
+	if _, err := a.peerSvc.AddPeer(b.url); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.peerSvc.AddPeer(a.url); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mine(t *testing.T, miner *devnetNode, data string) *service.Block {
+	t.Helper()
+
+	block, err := miner.blockChainSvc.NewBlock(context.Background(), data, miner.wallet.Address, -1, service.MiningOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func allHave(nodes []*devnetNode, hashes ...string) bool {
+	for _, n := range nodes {
+		for _, hash := range hashes {
+			if _, err := n.blockChainSvc.GetBlockByHash(hash); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// waitConverged waits until every node has the same head.
+func waitConverged(t *testing.T, nodes []*devnetNode) {
+	t.Helper()
+
+	waitFor(t, "nodes to converge", func() bool {
+		head := nodes[0].peerSvc.Status().Head
+		for _, n := range nodes[1:] {
+			if n.peerSvc.Status().Head != head {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(devnetTimeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s waiting for %s", devnetTimeout, what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
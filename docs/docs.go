@@ -15,9 +15,933 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/auto-miner/start": {
+            "post": {
+                "description": "Mine in the background every interval seconds and/or once the pool holds more than pool_threshold transactions, omitted fields keep the configured value",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auto-miner"
+                ],
+                "summary": "Start auto-miner",
+                "parameters": [
+                    {
+                        "description": "Auto-miner settings",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AutoMinerData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/auto-miner/status": {
+            "get": {
+                "description": "Get whether the background miner runs, its settings, the mining jobs it started and what triggered the last one",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auto-miner"
+                ],
+                "summary": "Auto-miner status",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/auto-miner/stop": {
+            "post": {
+                "description": "Stop the background miner",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auto-miner"
+                ],
+                "summary": "Stop auto-miner",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
         "/block": {
             "get": {
-                "description": "Get blocks",
+                "description": "Get blocks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get blocks",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/audit-chain": {
+            "post": {
+                "description": "Report every violation of every block of a chain, such as an imported chain, without replacing the local chain",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Audit chain",
+                "parameters": [
+                    {
+                        "description": "Chain",
+                        "name": "chain",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.Chain"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/branch/{hash}": {
+            "get": {
+                "description": "Get the blocks from the genesis block to the block hash and where they fork off the canonical chain",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get branch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Block hash",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/check-valid-chain": {
+            "get": {
+                "description": "Validate the local chain, with audit=true every violation of every block is reported",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Check valid chain",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Report every violation",
+                        "name": "audit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/export": {
+            "get": {
+                "description": "Stream the canonical chain as NDJSON, one block per line, or in the compact binary format",
+                "produces": [
+                    "application/x-ndjson",
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Export chain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ndjson or binary",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "First block number",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last block number, the head by default",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/get-block-policy": {
+            "get": {
+                "description": "Get the limits and the strategy used to pick pool transactions for a block",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get block policy",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/get-difficulty": {
+            "get": {
+                "description": "Get the difficulty set on this node, the retarget settings and the difficulty the next block must meet, with its bits and target under proof-of-work",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get difficulty",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/history": {
+            "get": {
+                "description": "Get the snapshots of the chain and pool taken before every reset, new genesis block, re-mine, restore and replace chain that rolls back blocks, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get chain history",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/history/{snapshotId}/diff": {
+            "get": {
+                "description": "Compare the blocks and pool transactions of a snapshot with the current chain",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Diff snapshot",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Snapshot id",
+                        "name": "snapshotId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/history/{snapshotId}/restore": {
+            "post": {
+                "description": "Bring back the chain and pool of a snapshot, the current chain and pool are snapshotted first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Restore snapshot",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Snapshot id",
+                        "name": "snapshotId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/import": {
+            "post": {
+                "description": "Validate an NDJSON or binary chain export block by block and add the valid blocks, a failed import keeps the blocks before the failure and resumes after them when it is sent again",
+                "consumes": [
+                    "application/x-ndjson",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Import chain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ndjson or binary, detected from the body by default",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/merkle-proof/{txHash}": {
+            "get": {
+                "description": "Get the proof that a mined transaction is part of the Merkle root of its block",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get Merkle proof",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction hash",
+                        "name": "txHash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/mine": {
+            "post": {
+                "description": "Start mining a block in the background from the pool transactions, a block_number of 0 mines on top of the tip. The job is polled through /block/mine/jobs/{jobId}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Mine block",
+                "parameters": [
+                    {
+                        "description": "Mine block data",
+                        "name": "block",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MineBlockData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/block/mine/jobs": {
+            "get": {
+                "description": "Get the running mining job and the last finished ones, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get mining jobs",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/mine/jobs/{jobId}": {
+            "get": {
+                "description": "Get the status and progress of a mining job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get mining job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job id",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/mine/jobs/{jobId}/cancel": {
+            "post": {
+                "description": "Stop a running mining job, nothing is added to the chain",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Cancel mining job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job id",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/mine/trace": {
+            "get": {
+                "description": "Stream sampled proof-of-work attempts of every mining run as server-sent events",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Mining trace",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/mining-stats": {
+            "get": {
+                "description": "Get the hash rate of the last nonce search and the worker count, mine with workers=1 to compare against single-threaded mining",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Mining stats",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/orphans": {
+            "get": {
+                "description": "Get the blocks of the block tree that are not on the canonical chain",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get orphans",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/reorgs": {
+            "get": {
+                "description": "Get the recent switches of the canonical head that rolled back blocks",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get reorgs",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/reward": {
+            "get": {
+                "description": "Get the reward schedule, the reward of the next block, when it halves next and the coins issued as rewards and faucet grants",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get reward",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/set-block-policy": {
+            "post": {
+                "description": "Set the limits and the strategy (oldest, fee or fair) used to pick pool transactions for a block, limits of 0 are unlimited",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Set block policy",
+                "parameters": [
+                    {
+                        "description": "Block policy",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.BlockPolicyData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/set-difficulty": {
+            "post": {
+                "description": "Set difficulty",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Set difficulty",
+                "parameters": [
+                    {
+                        "description": "Difficulty",
+                        "name": "difficulty",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.SetDifficultyData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/block/set-trace-sample-rate": {
+            "post": {
+                "description": "Stream one proof-of-work attempt in every sample_rate hashes to the mining trace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Set trace sample rate",
+                "parameters": [
+                    {
+                        "description": "Sample rate",
+                        "name": "sample_rate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.SetTraceSampleRateData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/set-workers": {
+            "post": {
+                "description": "Set how many goroutines search the nonce space of the next mining runs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Set workers",
+                "parameters": [
+                    {
+                        "description": "Workers",
+                        "name": "workers",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.SetWorkersData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/tips": {
+            "get": {
+                "description": "Get the blocks without children of every branch of the block tree, the heaviest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get tips",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/validators": {
+            "get": {
+                "description": "Get the proof-of-stake stakes and the proposer of the next block",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Get validators",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/verify-merkle-proof": {
+            "post": {
+                "description": "Check a Merkle proof of a transaction hash against a Merkle root",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Verify Merkle proof",
+                "parameters": [
+                    {
+                        "description": "Proof",
+                        "name": "proof",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.VerifyMerkleProofData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/block/{blockNumber}/remine": {
+            "post": {
+                "description": "Replace the data of a block and re-mine it and every block after it, progress is streamed as server-sent events. The re-mined chain replaces the block tree, the old chain is snapshotted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "block"
+                ],
+                "summary": "Re-mine from a block",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Block number",
+                        "name": "blockNumber",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Remine block data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RemineBlockData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/peer/": {
+            "get": {
+                "description": "Get the registered peers with the chain they reported last",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "peer"
+                ],
+                "summary": "Get peers",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "description": "Register another node by its base URL, it gets every block and pool transaction this node sees",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "peer"
+                ],
+                "summary": "Add peer",
+                "parameters": [
+                    {
+                        "description": "Peer",
+                        "name": "peer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PeerData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/peer/blocks": {
+            "post": {
+                "description": "Receive a block from another node, missing ancestors are fetched from the node at from when it is a registered peer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "peer"
+                ],
+                "summary": "Announce block",
+                "parameters": [
+                    {
+                        "description": "Block",
+                        "name": "block",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AnnounceBlockData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/peer/blocks/{hash}": {
+            "get": {
+                "description": "Get any block of the block tree by its hash, side branches included",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "peer"
+                ],
+                "summary": "Get block by hash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Block hash",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/peer/remove": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "peer"
+                ],
+                "summary": "Remove peer",
+                "parameters": [
+                    {
+                        "description": "Peer",
+                        "name": "peer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PeerData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/peer/status": {
+            "get": {
+                "description": "Get the height, head and total work of the canonical chain of this node",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "peer"
+                ],
+                "summary": "Node status",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/peer/sync": {
+            "post": {
+                "description": "Replace the local chain with the chain of the peer with the most work, or of the given peer, which must be registered first",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,9 +949,19 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "block"
+                    "peer"
+                ],
+                "summary": "Sync with peers",
+                "parameters": [
+                    {
+                        "description": "Peer",
+                        "name": "peer",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PeerData"
+                        }
+                    }
                 ],
-                "summary": "Get blocks",
                 "responses": {
                     "200": {
                         "description": "OK"
@@ -35,9 +969,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/block/set-difficulty": {
+        "/peer/transactions": {
             "post": {
-                "description": "Set difficulty",
+                "description": "Receive a pool transaction from another node",
                 "consumes": [
                     "application/json"
                 ],
@@ -45,29 +979,238 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "block"
+                    "peer"
                 ],
-                "summary": "Set difficulty",
+                "summary": "Announce transaction",
                 "parameters": [
                     {
-                        "description": "Difficulty",
-                        "name": "difficulty",
+                        "description": "Transaction",
+                        "name": "transaction",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.SetDifficultyData"
+                            "$ref": "#/definitions/dto.AnnounceTransactionData"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created"
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/sandbox/": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Get sandboxes",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a chain of its own, every route of the node is served for it under /sandbox/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Create sandbox",
+                "parameters": [
+                    {
+                        "description": "Sandbox",
+                        "name": "sandbox",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateSandboxData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/sandbox/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Get sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop the sandbox and delete its chain, pool and difficulty",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Delete sandbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sandbox id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
                     }
                 }
             }
         }
     },
     "definitions": {
+        "dto.AnnounceBlockData": {
+            "type": "object",
+            "required": [
+                "block"
+            ],
+            "properties": {
+                "block": {
+                    "$ref": "#/definitions/service.Block"
+                },
+                "from": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AnnounceTransactionData": {
+            "type": "object",
+            "required": [
+                "transaction"
+            ],
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/service.Transaction"
+                }
+            }
+        },
+        "dto.AutoMinerData": {
+            "type": "object",
+            "properties": {
+                "interval": {
+                    "type": "integer"
+                },
+                "miner_address": {
+                    "type": "string"
+                },
+                "pool_threshold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.BlockPolicyData": {
+            "type": "object",
+            "required": [
+                "strategy"
+            ],
+            "properties": {
+                "max_bytes": {
+                    "type": "integer"
+                },
+                "max_transactions": {
+                    "type": "integer"
+                },
+                "strategy": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreateSandboxData": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MineBlockData": {
+            "type": "object",
+            "required": [
+                "miner_address"
+            ],
+            "properties": {
+                "block_number": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "string"
+                },
+                "miner_address": {
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.PeerData": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RemineBlockData": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "string"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.SetDifficultyData": {
             "type": "object",
             "required": [
@@ -78,6 +1221,183 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "dto.SetTraceSampleRateData": {
+            "type": "object",
+            "required": [
+                "sample_rate"
+            ],
+            "properties": {
+                "sample_rate": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.SetWorkersData": {
+            "type": "object",
+            "required": [
+                "workers"
+            ],
+            "properties": {
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.VerifyMerkleProofData": {
+            "type": "object",
+            "required": [
+                "merkle_root",
+                "tx_hash"
+            ],
+            "properties": {
+                "merkle_root": {
+                    "type": "string"
+                },
+                "proof": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/util.MerkleProofStep"
+                    }
+                },
+                "tx_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.Block": {
+            "type": "object",
+            "properties": {
+                "binary": {
+                    "type": "string"
+                },
+                "bits": {
+                    "description": "compact proof-of-work target",
+                    "type": "integer"
+                },
+                "block_number": {
+                    "type": "integer"
+                },
+                "chain_work": {
+                    "description": "hex total work of the chain up to this block",
+                    "type": "string"
+                },
+                "data": {
+                    "type": "string"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "merkle_root": {
+                    "type": "string"
+                },
+                "miner": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "integer"
+                },
+                "parent_hash": {
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "block producer signature over Hash, proof-of-authority and proof-of-stake only",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.Transaction"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.Chain": {
+            "type": "object",
+            "properties": {
+                "blocks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.Block"
+                    }
+                }
+            }
+        },
+        "service.Transaction": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/service.TransactionType"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.TransactionType": {
+            "type": "string",
+            "enum": [
+                "",
+                "stake",
+                "unstake"
+            ],
+            "x-enum-varnames": [
+                "TransferTransaction",
+                "StakeTransaction",
+                "UnstakeTransaction"
+            ]
+        },
+        "util.MerklePosition": {
+            "type": "string",
+            "enum": [
+                "left",
+                "right"
+            ],
+            "x-enum-varnames": [
+                "MerkleLeft",
+                "MerkleRight"
+            ]
+        },
+        "util.MerkleProofStep": {
+            "type": "object",
+            "properties": {
+                "hash": {
+                    "type": "string"
+                },
+                "position": {
+                    "$ref": "#/definitions/util.MerklePosition"
+                }
+            }
         }
     }
 }`
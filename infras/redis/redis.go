@@ -1,32 +1,26 @@
 package redis
 
 import (
-	"blockchain-backend/config"
 	"context"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var (
-	ChainKey                  = "CHAIN"
-	DifficultyKey             = "DIFFICULTY"
-	TransactionPoolKey        = "TRANSACTION_POOL"
-	ChannelSyncNodeKey        = "BLOCKCHAIN"
-	ChannelSyncTransactionKey = "TRANSACTION"
-	CurrentBlockCrawledKey    = "CURRENT_BLOCK_CRAWLED"
-	HistoryTransactionsKey    = "HISTORY_TRANSACTIONS"
-)
-
 var Ctx = context.Background()
 
 type IRedis interface {
 	Get(key string) string
 	Set(key string, value string)
+	SetAtomic(values map[string]string, deleted []string)
+	Del(keys ...string)
 	SetSet(key string, value string)
 	GetSet(key string) []string
+	Keys(prefix string) []string
 	Publish(channel string, message string)
-	Subscribe(channel string) *redis.PubSub
+	Subscribe(channels ...string) *Subscription
 }
 
 type redisService struct {
@@ -39,21 +33,19 @@ func NewRedisService(client *redis.Client) IRedis {
 	}
 }
 
-func Connect() *redis.Client {
-	// Connect to Redis
+// Connect opens a client to the redis server at url and checks that it answers.
+func Connect(url string) (*redis.Client, error) {
 	redisClient := redis.NewClient(&redis.Options{
-		Addr: config.ConfigEnv.RedisUrl,
+		Addr: url,
 	})
 
 	// Ping the Redis server and check if any errors occurred
-	_, err := redisClient.Ping(Ctx).Result()
-	if err != nil {
-		panic(err)
+	if _, err := redisClient.Ping(Ctx).Result(); err != nil {
+		return nil, fmt.Errorf("connect to redis at %s: %w", url, err)
 	}
 
-	// Print the Redis server's status
 	fmt.Println("Connected to Redis!")
-	return redisClient
+	return redisClient, nil
 }
 
 func (rs *redisService) Get(key string) string {
@@ -71,6 +63,32 @@ func (rs *redisService) Set(key string, value string) {
 	}
 }
 
+// SetAtomic sets values and deletes the deleted keys in one MULTI/EXEC transaction.
+func (rs *redisService) SetAtomic(values map[string]string, deleted []string) {
+	_, err := rs.client.TxPipelined(Ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(Ctx, key, value, 0)
+		}
+		if len(deleted) > 0 {
+			pipe.Del(Ctx, deleted...)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (rs *redisService) Del(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	err := rs.client.Del(Ctx, keys...).Err()
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (rs *redisService) SetSet(key string, value string) {
 	err := rs.client.SAdd(Ctx, key, value).Err()
 	if err != nil {
@@ -86,16 +104,48 @@ func (rs *redisService) GetSet(key string) []string {
 	return val
 }
 
+// Keys scans for the keys starting with prefix, glob characters in prefix match literally.
+func (rs *redisService) Keys(prefix string) []string {
+	pattern := globEscaper.Replace(prefix) + "*"
+	keys := []string{}
+	iter := rs.client.Scan(Ctx, 0, pattern, 0).Iterator()
+	for iter.Next(Ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Publish is best effort, the other nodes catch up on later messages, so a failure is only logged.
 func (rs *redisService) Publish(channel string, message string) {
 	err := rs.client.Publish(Ctx, channel, message).Err()
 	if err != nil {
-		panic(err)
+		log.Println("Publish to", channel, "failed:", err)
 	}
 }
 
-func (rs *redisService) Subscribe(channel string) *redis.PubSub {
-	pubsub := rs.client.Subscribe(Ctx, channel)
-	return pubsub
+func (rs *redisService) Subscribe(channels ...string) *Subscription {
+	return &Subscription{pubsub: rs.client.Subscribe(Ctx, channels...)}
 }
 
-var RedisService = NewRedisService(Connect())
+// Subscription reads the messages published on the subscribed channels.
+type Subscription struct {
+	pubsub *redis.PubSub
+}
+
+// ReceiveMessage waits for the next message, an error means the subscription is lost or ctx is done.
+func (s *Subscription) ReceiveMessage(ctx context.Context) (channel string, payload string, err error) {
+	msg, err := s.pubsub.ReceiveMessage(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return msg.Channel, msg.Payload, nil
+}
+
+func (s *Subscription) Close() error {
+	return s.pubsub.Close()
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// fileRecord is one line of the append-only log, either a set member added to Key or a SetAtomic.
+type fileRecord struct {
+	Values  map[string]string `json:"values,omitempty"`
+	Deleted []string          `json:"deleted,omitempty"`
+	Key     string            `json:"key,omitempty"`
+	Member  string            `json:"member,omitempty"`
+}
+
+// fileStorage serves reads from memory and appends every write to a log file that is synced before the write
+// returns. The log is replayed when the node starts and compacted to one record per key, a torn last line
+// left by a crash is dropped. Only one node may open the file.
+type fileStorage struct {
+	*memoryStorage
+	path string
+	file *os.File
+}
+
+func OpenFileStorage(path string) (IStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	fs := &fileStorage{
+		memoryStorage: newMemoryStorage(),
+		path:          path,
+	}
+	if err := fs.replay(); err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	if err := fs.compact(); err != nil {
+		return nil, fmt.Errorf("compact %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fs.file = file
+
+	return fs, nil
+}
+
+func (fs *fileStorage) replay() error {
+	file, err := os.Open(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				log.Println("Dropping the torn last record of", fs.path)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var record fileRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		fs.applyRecord(record)
+	}
+}
+
+// applyRecord must be called with fs.mu held or before the storage is shared.
+func (fs *fileStorage) applyRecord(record fileRecord) {
+	if record.Key != "" {
+		fs.add(record.Key, record.Member)
+		return
+	}
+	fs.apply(record.Values, record.Deleted)
+}
+
+// compact rewrites the log as one record per key next to it and renames it over the log.
+func (fs *fileStorage) compact() error {
+	tmpPath := fs.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	if len(fs.values) > 0 {
+		if err := encoder.Encode(fileRecord{Values: fs.values}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	keys := make([]string, 0, len(fs.sets))
+	for key := range fs.sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for member := range fs.sets[key] {
+			if err := encoder.Encode(fileRecord{Key: key, Member: member}); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, fs.path)
+}
+
+// append writes record to the log, a node that cannot persist its state stops like the redis backend does.
+// It must be called with fs.mu held so records are written in the order they are applied.
+func (fs *fileStorage) append(record fileRecord) {
+	line, _ := json.Marshal(record)
+	if _, err := fs.file.Write(append(line, '\n')); err != nil {
+		panic(err)
+	}
+	if err := fs.file.Sync(); err != nil {
+		panic(err)
+	}
+}
+
+func (fs *fileStorage) Set(key string, value string) {
+	fs.SetAtomic(map[string]string{key: value}, nil)
+}
+
+func (fs *fileStorage) SetAtomic(values map[string]string, deleted []string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.append(fileRecord{Values: values, Deleted: deleted})
+	fs.apply(values, deleted)
+}
+
+func (fs *fileStorage) Del(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	fs.SetAtomic(nil, keys)
+}
+
+func (fs *fileStorage) SetSet(key string, value string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.append(fileRecord{Key: key, Member: value})
+	fs.add(key, value)
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// memoryStorage keeps everything in maps, the state is lost when the node stops.
+type memoryStorage struct {
+	mu     sync.RWMutex
+	values map[string]string
+	sets   map[string]map[string]struct{}
+}
+
+func NewMemoryStorage() IStorage {
+	return newMemoryStorage()
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		values: make(map[string]string),
+		sets:   make(map[string]map[string]struct{}),
+	}
+}
+
+func (ms *memoryStorage) Get(key string) string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.values[key]
+}
+
+func (ms *memoryStorage) Set(key string, value string) {
+	ms.SetAtomic(map[string]string{key: value}, nil)
+}
+
+func (ms *memoryStorage) SetAtomic(values map[string]string, deleted []string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.apply(values, deleted)
+}
+
+// apply must be called with ms.mu held.
+func (ms *memoryStorage) apply(values map[string]string, deleted []string) {
+	for key, value := range values {
+		delete(ms.sets, key)
+		ms.values[key] = value
+	}
+	for _, key := range deleted {
+		delete(ms.values, key)
+		delete(ms.sets, key)
+	}
+}
+
+func (ms *memoryStorage) Del(keys ...string) {
+	ms.SetAtomic(nil, keys)
+}
+
+func (ms *memoryStorage) SetSet(key string, value string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.add(key, value)
+}
+
+// add must be called with ms.mu held.
+func (ms *memoryStorage) add(key string, value string) {
+	delete(ms.values, key)
+	if ms.sets[key] == nil {
+		ms.sets[key] = make(map[string]struct{})
+	}
+	ms.sets[key][value] = struct{}{}
+}
+
+func (ms *memoryStorage) GetSet(key string) []string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	members := make([]string, 0, len(ms.sets[key]))
+	for member := range ms.sets[key] {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func (ms *memoryStorage) Keys(prefix string) []string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	keys := []string{}
+	for key := range ms.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range ms.sets {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (ms *memoryStorage) Publish(channel string, message string) {
+}
@@ -0,0 +1,69 @@
+package storage
+
+import "strings"
+
+// namespacedStorage prefixes every key and channel, so several chains share one backend without seeing each
+// other. Keys lists the keys without the prefix.
+type namespacedStorage struct {
+	store  IStorage
+	prefix string
+}
+
+func Namespace(store IStorage, prefix string) IStorage {
+	return &namespacedStorage{
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+func (ns *namespacedStorage) key(key string) string {
+	return ns.prefix + key
+}
+
+func (ns *namespacedStorage) keys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = ns.key(key)
+	}
+	return prefixed
+}
+
+func (ns *namespacedStorage) Get(key string) string {
+	return ns.store.Get(ns.key(key))
+}
+
+func (ns *namespacedStorage) Set(key string, value string) {
+	ns.store.Set(ns.key(key), value)
+}
+
+func (ns *namespacedStorage) SetAtomic(values map[string]string, deleted []string) {
+	prefixed := make(map[string]string, len(values))
+	for key, value := range values {
+		prefixed[ns.key(key)] = value
+	}
+	ns.store.SetAtomic(prefixed, ns.keys(deleted))
+}
+
+func (ns *namespacedStorage) Del(keys ...string) {
+	ns.store.Del(ns.keys(keys)...)
+}
+
+func (ns *namespacedStorage) SetSet(key string, value string) {
+	ns.store.SetSet(ns.key(key), value)
+}
+
+func (ns *namespacedStorage) GetSet(key string) []string {
+	return ns.store.GetSet(ns.key(key))
+}
+
+func (ns *namespacedStorage) Keys(prefix string) []string {
+	keys := ns.store.Keys(ns.key(prefix))
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, ns.prefix)
+	}
+	return keys
+}
+
+func (ns *namespacedStorage) Publish(channel string, message string) {
+	ns.store.Publish(ns.key(channel), message)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"blockchain-backend/config"
+	"blockchain-backend/infras/redis"
+	"context"
+	"fmt"
+)
+
+var (
+	ChainKey                  = "CHAIN"
+	ChainVersionKey           = "CHAIN_VERSION"
+	HeadKey                   = "HEAD"
+	BlockKeyPrefix            = "BLOCK:"
+	BlockNumberKeyPrefix      = "BLOCK_NUMBER:"
+	BlockHashesKey            = "BLOCK_HASHES"
+	DifficultyKey             = "DIFFICULTY"
+	TransactionPoolKey        = "TRANSACTION_POOL"
+	ChannelSyncNodeKey        = "BLOCKCHAIN"
+	ChannelSyncTransactionKey = "TRANSACTION"
+	CurrentBlockCrawledKey    = "CURRENT_BLOCK_CRAWLED"
+	HistoryTransactionsKey    = "HISTORY_TRANSACTIONS"
+	SandboxesKey              = "SANDBOXES"
+	SandboxKeyPrefix          = "SANDBOX:"
+	SnapshotsKey              = "SNAPSHOTS"
+	SnapshotKeyPrefix         = "SNAPSHOT:"
+	NodeKeyPrefix             = "NODE:"
+)
+
+const (
+	Memory = "memory"
+	Redis  = "redis"
+	File   = "file"
+
+	defaultFilePath = "data/blab.log"
+)
+
+// IStorage is the key value store of the node state. String keys and set keys share one namespace, Get of a
+// missing key is "" and Del removes keys of either kind.
+type IStorage interface {
+	Get(key string) string
+	Set(key string, value string)
+	// SetAtomic sets values and deletes the deleted keys as one write, readers see all of it or none
+	SetAtomic(values map[string]string, deleted []string)
+	Del(keys ...string)
+	SetSet(key string, value string)
+	GetSet(key string) []string
+	// Keys lists the string and set keys starting with prefix
+	Keys(prefix string) []string
+	// Publish announces message to the other nodes, a no-op for the single node backends
+	Publish(channel string, message string)
+}
+
+// IPubSub is implemented by the backends several nodes share, they hear what the other nodes Publish.
+type IPubSub interface {
+	Subscribe(channels ...string) ISubscription
+}
+
+type ISubscription interface {
+	// ReceiveMessage waits for the next message, an error means the subscription is lost or ctx is done
+	ReceiveMessage(ctx context.Context) (channel string, payload string, err error)
+	Close() error
+}
+
+// redisStorage adapts the redis service to IStorage and IPubSub.
+type redisStorage struct {
+	redis.IRedis
+}
+
+func (rs *redisStorage) Subscribe(channels ...string) ISubscription {
+	return rs.IRedis.Subscribe(channels...)
+}
+
+// New opens the storage backend selected by cfg.Storage.
+func New(cfg config.Config) (IStorage, error) {
+	switch cfg.Storage {
+	case "", Redis:
+		client, err := redis.Connect(cfg.RedisUrl)
+		if err != nil {
+			return nil, err
+		}
+		return &redisStorage{IRedis: redis.NewRedisService(client)}, nil
+	case Memory:
+		return NewMemoryStorage(), nil
+	case File:
+		path := cfg.StoragePath
+		if path == "" {
+			path = defaultFilePath
+		}
+		return OpenFileStorage(path)
+	default:
+		return nil, fmt.Errorf("storage must be one of %s, %s, %s", Redis, Memory, File)
+	}
+}
@@ -2,84 +2,78 @@ package main
 
 import (
 	"blockchain-backend/config"
-	"blockchain-backend/controller"
-	docs "blockchain-backend/docs"
-	"blockchain-backend/infras/redis"
+	"blockchain-backend/infras/storage"
 	"blockchain-backend/service"
-	"encoding/json"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
-	"github.com/go-playground/validator/v10"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 	"log"
-	"time"
+	"os"
+	"strings"
 )
 
-var timestampInSeconds validator.Func = func(fl validator.FieldLevel) bool {
-	// validate that the timestamp is in seconds
-	timestamp := fl.Field().Int()
-	return timestamp > 0 && timestamp < time.Now().Unix()
+// openNode opens the configured storage and loads the node on top of it. A node with a NODE_ID on storage other
+// nodes can publish to keeps its state apart from theirs.
+func openNode() (*node, error) {
+	store, err := storage.New(config.ConfigEnv)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := store.(storage.IPubSub); ok && config.ConfigEnv.NodeID != "" {
+		return newSharedNode(store, config.ConfigEnv.NodeID, config.ConfigEnv.PeerURL)
+	}
+	return newNode(store, config.ConfigEnv.PeerURL)
 }
 
 func main() {
 
 	port := config.ConfigEnv.Port
-	engine := gin.Default()
 
-	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		err := v.RegisterValidation("timestampInSeconds", timestampInSeconds)
-		if err != nil {
-			return
+	// subcommands run against the stored chain and exit
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
 		}
+		return
 	}
 
-	engine.ForwardedByClientIP = true
-	err := engine.SetTrustedProxies([]string{"127.0.0.1"})
+	n, err := openNode()
 	if err != nil {
-		return
+		log.Fatal(err)
 	}
 
-	engine.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"*"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	docs.SwaggerInfo.BasePath = "/"
-
-	engine.GET("/", func(c *gin.Context) {
-
-		c.JSON(200, gin.H{
-			"message": "Welcome to the blab blockchain",
-		})
-	})
-
-	// sync block
-	var chain = service.Chain{}
-	blockChain := redis.RedisService.Get(redis.ChainKey)
-	if blockChain != "" {
-		err = json.Unmarshal([]byte(blockChain), &chain)
+	// sync node, only nodes with their own state on the shared storage publish to each other
+	if n.shared != nil {
+		nodeSyncSvc, err := service.NewNodeSyncService(n.blockChainSvc, n.transactionPoolSvc, n.blockStore, n.shared, config.ConfigEnv.NodeID)
 		if err != nil {
 			log.Fatal(err)
 		}
+		if err := nodeSyncSvc.Start(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	transactionSvc := service.NewTransactionService()
-	transactionPoolSvc := service.NewTransactionPoolService(transactionSvc)
-	blockSvc := service.NewBlockService(transactionPoolSvc)
-	blockChainSvc := service.NewBlockchainService(blockSvc, transactionPoolSvc, chain)
-	walletSvc := service.NewWalletService(blockChainSvc)
-	//ganacheSvc := service.NewGanacheService()
+	// peers
+	for _, peerURL := range strings.Split(config.ConfigEnv.Peers, ",") {
+		if strings.TrimSpace(peerURL) == "" {
+			continue
+		}
+		if _, err := n.peerSvc.AddPeer(peerURL); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if config.ConfigEnv.PeerSyncInterval > 0 {
+		if err := n.peerSvc.Start(config.ConfigEnv.PeerSyncInterval); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	// sync node
-	//go func() {
-	//	blockChainSvc.SyncNode(redis.RedisService.Subscribe(redis.ChannelSyncNodeKey))
-	//}()
+	// sandboxes, chains of their own under /sandbox/:id
+	n.sandboxes = newSandboxes(service.NewSandboxService(n.store))
+
+	// auto-miner
+	if config.ConfigEnv.AutoMineEnabled {
+		if err := n.autoMinerSvc.Start(n.autoMinerSvc.DefaultConfig()); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// cron crawl block
 	//go func() {
@@ -89,22 +83,10 @@ func main() {
 	//	}
 	//}()
 
-	walletController := controller.NewWalletController(walletSvc, transactionPoolSvc)
-	transactionController := controller.NewTransactionController(transactionSvc, transactionPoolSvc, blockChainSvc, walletSvc)
-	blockController := controller.NewBlockController(blockSvc, blockChainSvc, transactionPoolSvc, transactionSvc)
-	ganacheController := controller.NewGanacheController()
-
-	walletGroup := engine.Group("/wallet")
-	transactionGroup := engine.Group("/transaction")
-	blockGroup := engine.Group("/block")
-	ganacheGroup := engine.Group("/ganache")
-
-	walletController.SetupRoutes(walletGroup)
-	transactionController.SetupRoutes(transactionGroup)
-	blockController.SetupRoutes(blockGroup)
-	ganacheController.SetupRoutes(ganacheGroup)
-
-	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	engine, err := n.router()
+	if err != nil {
+		log.Fatal(err)
+	}
 	if err := engine.Run(
 		":" + port,
 	); err != nil {
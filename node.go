@@ -0,0 +1,167 @@
+package main
+
+import (
+	"blockchain-backend/controller"
+	docs "blockchain-backend/docs"
+	"blockchain-backend/infras/storage"
+	"blockchain-backend/service"
+	"encoding/json"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"time"
+)
+
+// node is the services of one blab node on top of its storage.
+type node struct {
+	store storage.IStorage
+	// shared is the storage of all nodes that publish to each other, nil when the node has its storage to itself
+	shared             storage.IStorage
+	blockStore         service.IBlockStore
+	transactionSvc     service.ITransactionService
+	transactionPoolSvc service.ITransactionPoolService
+	blockSvc           service.IBlockService
+	blockChainSvc      service.IBlockchainService
+	walletSvc          service.IWalletService
+	miningJobSvc       service.IMiningJobService
+	autoMinerSvc       service.IAutoMinerService
+	peerSvc            service.IPeerService
+	// sandboxes is only set on the node serving the API, the nodes of the sandboxes have none
+	sandboxes *sandboxes
+}
+
+// newNode loads the canonical chain from store and migrates it, peerURL is the base URL other nodes reach this node at.
+func newNode(store storage.IStorage, peerURL string) (*node, error) {
+	return loadNode(store, service.NewBlockStore(store), peerURL)
+}
+
+// newSharedNode keeps the state of node nodeID under NODE:<nodeID>: in shared, only the blocks by hash are
+// shared with the other nodes, so a reset on one node leaves the chains of the others alone.
+func newSharedNode(shared storage.IStorage, nodeID string, peerURL string) (*node, error) {
+	store := storage.Namespace(shared, storage.NodeKeyPrefix+nodeID+":")
+	n, err := loadNode(store, service.NewSharedBlockStore(store, shared), peerURL)
+	if err != nil {
+		return nil, err
+	}
+	n.shared = shared
+	return n, nil
+}
+
+func loadNode(store storage.IStorage, blockStore service.IBlockStore, peerURL string) (*node, error) {
+	// load the canonical chain block by block, chains stored before the block store are one JSON blob
+	var chain = service.Chain{}
+	head, ok, err := blockStore.Head()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for blockNumber := int64(1); blockNumber <= head.BlockNumber; blockNumber++ {
+			block, err := blockStore.BlockByNumber(blockNumber)
+			if err != nil {
+				return nil, err
+			}
+			chain.Blocks = append(chain.Blocks, block)
+		}
+	} else if blockChain := store.Get(storage.ChainKey); blockChain != "" {
+		if err := json.Unmarshal([]byte(blockChain), &chain); err != nil {
+			return nil, err
+		}
+	}
+
+	transactionSvc := service.NewTransactionService()
+	transactionPoolSvc := service.NewTransactionPoolService(transactionSvc, store)
+	blockSvc := service.NewBlockService(transactionSvc, store)
+	blockChainSvc := service.NewBlockchainService(blockSvc, transactionSvc, transactionPoolSvc, blockStore, store, chain)
+	if err := blockChainSvc.Migrate(); err != nil {
+		return nil, err
+	}
+	miningJobSvc := service.NewMiningJobService(blockChainSvc)
+	//ganacheSvc := service.NewGanacheService(store)
+
+	return &node{
+		store:              store,
+		blockStore:         blockStore,
+		transactionSvc:     transactionSvc,
+		transactionPoolSvc: transactionPoolSvc,
+		blockSvc:           blockSvc,
+		blockChainSvc:      blockChainSvc,
+		walletSvc:          service.NewWalletService(blockChainSvc),
+		miningJobSvc:       miningJobSvc,
+		autoMinerSvc:       service.NewAutoMinerService(miningJobSvc, transactionPoolSvc),
+		peerSvc:            service.NewPeerService(blockChainSvc, transactionPoolSvc, peerURL),
+	}, nil
+}
+
+var timestampInSeconds validator.Func = func(fl validator.FieldLevel) bool {
+	// validate that the timestamp is in seconds
+	timestamp := fl.Field().Int()
+	return timestamp > 0 && timestamp < time.Now().Unix()
+}
+
+// router serves the API of the node.
+func (n *node) router() (*gin.Engine, error) {
+	engine := gin.Default()
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		if err := v.RegisterValidation("timestampInSeconds", timestampInSeconds); err != nil {
+			return nil, err
+		}
+	}
+
+	engine.ForwardedByClientIP = true
+	if err := engine.SetTrustedProxies([]string{"127.0.0.1"}); err != nil {
+		return nil, err
+	}
+
+	engine.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"*"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	docs.SwaggerInfo.BasePath = "/"
+
+	engine.GET("/", func(c *gin.Context) {
+
+		c.JSON(200, gin.H{
+			"message": "Welcome to the blab blockchain",
+		})
+	})
+
+	walletController := controller.NewWalletController(n.walletSvc, n.transactionSvc, n.transactionPoolSvc)
+	transactionController := controller.NewTransactionController(n.transactionSvc, n.transactionPoolSvc, n.blockChainSvc, n.walletSvc)
+	blockController := controller.NewBlockController(n.blockSvc, n.blockChainSvc, n.transactionPoolSvc, n.transactionSvc, n.miningJobSvc)
+	ganacheController := controller.NewGanacheController(n.store)
+	autoMinerController := controller.NewAutoMinerController(n.autoMinerSvc)
+	peerController := controller.NewPeerController(n.peerSvc, n.blockChainSvc)
+
+	walletGroup := engine.Group("/wallet")
+	transactionGroup := engine.Group("/transaction")
+	blockGroup := engine.Group("/block")
+	ganacheGroup := engine.Group("/ganache")
+	autoMinerGroup := engine.Group("/auto-miner")
+	peerGroup := engine.Group("/peer")
+
+	walletController.SetupRoutes(walletGroup)
+	transactionController.SetupRoutes(transactionGroup)
+	blockController.SetupRoutes(blockGroup)
+	ganacheController.SetupRoutes(ganacheGroup)
+	autoMinerController.SetupRoutes(autoMinerGroup)
+	peerController.SetupRoutes(peerGroup)
+
+	if n.sandboxes != nil {
+		sandboxController := controller.NewSandboxController(n.sandboxes.sandboxSvc)
+		sandboxGroup := engine.Group("/sandbox")
+		sandboxController.SetupRoutes(sandboxGroup)
+		sandboxGroup.Any("/:id/*path", n.sandboxes.serve())
+	}
+
+	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	return engine, nil
+}
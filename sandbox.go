@@ -0,0 +1,112 @@
+package main
+
+import (
+	"blockchain-backend/service"
+	"errors"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sandboxes serves every route of a node for each sandbox under /sandbox/:id. The node of a sandbox runs on the
+// storage of the sandbox and is loaded on the first request to it. A sandbox that is being deleted stays in
+// deleted until it is created again, so a request racing the delete cannot load its node back.
+type sandboxes struct {
+	mu         sync.Mutex
+	sandboxSvc service.ISandboxService
+	nodes      map[string]*sandboxNode
+	deleted    map[string]bool
+}
+
+type sandboxNode struct {
+	*node
+	engine *gin.Engine
+}
+
+func newSandboxes(sandboxSvc service.ISandboxService) *sandboxes {
+	s := &sandboxes{
+		sandboxSvc: sandboxSvc,
+		nodes:      make(map[string]*sandboxNode),
+		deleted:    make(map[string]bool),
+	}
+	sandboxSvc.OnCreateSandbox(s.created)
+	sandboxSvc.OnDeleteSandbox(s.close)
+	return s
+}
+
+// open is the node of sandbox id, service.ErrSandboxNotFound when the sandbox does not exist or is being deleted.
+func (s *sandboxes) open(id string) (*sandboxNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.deleted[id] {
+		return nil, service.ErrSandboxNotFound
+	}
+	if n, ok := s.nodes[id]; ok {
+		return n, nil
+	}
+	if _, err := s.sandboxSvc.GetSandbox(id); err != nil {
+		return nil, err
+	}
+
+	n, err := newNode(s.sandboxSvc.Store(id), "")
+	if err != nil {
+		return nil, err
+	}
+	engine, err := n.router()
+	if err != nil {
+		return nil, err
+	}
+	s.nodes[id] = &sandboxNode{node: n, engine: engine}
+	return s.nodes[id], nil
+}
+
+func (s *sandboxes) created(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.deleted, id)
+}
+
+// close stops what runs in the background of a sandbox that is being deleted.
+func (s *sandboxes) close(id string) {
+	s.mu.Lock()
+	n, ok := s.nodes[id]
+	delete(s.nodes, id)
+	s.deleted[id] = true
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	_ = n.autoMinerSvc.Stop()
+	n.peerSvc.Stop()
+	for _, job := range n.miningJobSvc.List() {
+		if job.Status == service.MiningJobRunning {
+			_, _ = n.miningJobSvc.Cancel(job.ID)
+		}
+	}
+}
+
+// serve hands the request to the node of the sandbox with /sandbox/:id cut from its path.
+func (s *sandboxes) serve() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		n, err := s.open(c.Param("id"))
+		if errors.Is(err, service.ErrSandboxNotFound) {
+			c.JSON(404, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		request := c.Request.Clone(c.Request.Context())
+		request.URL.Path = c.Param("path")
+		request.URL.RawPath = ""
+		n.engine.ServeHTTP(c.Writer, request)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"blockchain-backend/config"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// AutoMinerConfig sets when the auto-miner produces blocks, a trigger of 0 is disabled.
+type AutoMinerConfig struct {
+	Interval      int64  `json:"interval"`       // seconds between blocks
+	PoolThreshold int    `json:"pool_threshold"` // mine once the pool holds more transactions than this
+	MinerAddress  string `json:"miner_address"`
+}
+
+type AutoMinerStatus struct {
+	Running       bool            `json:"running"`
+	Config        AutoMinerConfig `json:"config"`
+	StartedAt     int64           `json:"started_at,omitempty"`
+	JobsStarted   int64           `json:"jobs_started"`
+	LastJobID     string          `json:"last_job_id,omitempty"`
+	LastTrigger   string          `json:"last_trigger,omitempty"`
+	LastTriggerAt int64           `json:"last_trigger_at,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+type IAutoMinerService interface {
+	Start(cfg AutoMinerConfig) error
+	Stop() error
+	Status() AutoMinerStatus
+	DefaultConfig() AutoMinerConfig
+}
+
+type autoMinerService struct {
+	mu                 sync.Mutex
+	cron               *cron.Cron
+	status             AutoMinerStatus
+	miningJobSvc       IMiningJobService
+	transactionPoolSvc ITransactionPoolService
+}
+
+func NewAutoMinerService(miningJobSvc IMiningJobService, transactionPoolSvc ITransactionPoolService) IAutoMinerService {
+	return &autoMinerService{
+		miningJobSvc:       miningJobSvc,
+		transactionPoolSvc: transactionPoolSvc,
+	}
+}
+
+// DefaultConfig is the auto-miner configuration from the environment.
+func (ams *autoMinerService) DefaultConfig() AutoMinerConfig {
+	return AutoMinerConfig{
+		Interval:      config.ConfigEnv.AutoMineInterval,
+		PoolThreshold: config.ConfigEnv.AutoMinePoolThreshold,
+		MinerAddress:  config.ConfigEnv.AutoMineAddress,
+	}
+}
+
+func (ams *autoMinerService) Start(cfg AutoMinerConfig) error {
+	if cfg.MinerAddress == "" {
+		return fmt.Errorf("miner address is required")
+	}
+	if cfg.Interval < 0 || cfg.PoolThreshold < 0 {
+		return fmt.Errorf("interval and pool threshold must not be negative")
+	}
+	if cfg.Interval == 0 && cfg.PoolThreshold == 0 {
+		return fmt.Errorf("either interval or pool threshold must be set")
+	}
+
+	ams.mu.Lock()
+	defer ams.mu.Unlock()
+
+	if ams.cron != nil {
+		return fmt.Errorf("auto-miner is already running")
+	}
+
+	c := cron.New()
+	if cfg.Interval > 0 {
+		if _, err := c.AddFunc(fmt.Sprintf("@every %ds", cfg.Interval), func() {
+			ams.mine("interval")
+		}); err != nil {
+			return err
+		}
+	}
+	if cfg.PoolThreshold > 0 {
+		if _, err := c.AddFunc("@every 1s", func() {
+			if len(ams.transactionPoolSvc.GetTransactions()) > cfg.PoolThreshold {
+				ams.mine("pool threshold")
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	ams.cron = c
+	ams.status = AutoMinerStatus{
+		Running:   true,
+		Config:    cfg,
+		StartedAt: time.Now().Unix(),
+	}
+	c.Start()
+
+	log.Println("Auto-miner started for", cfg.MinerAddress)
+	return nil
+}
+
+func (ams *autoMinerService) Stop() error {
+	ams.mu.Lock()
+	c := ams.cron
+	if c == nil {
+		ams.mu.Unlock()
+		return fmt.Errorf("auto-miner is not running")
+	}
+	ams.cron = nil
+	ams.status.Running = false
+	ams.mu.Unlock()
+
+	// wait for a trigger that is starting a job right now, the job itself keeps running
+	<-c.Stop().Done()
+
+	log.Println("Auto-miner stopped")
+	return nil
+}
+
+func (ams *autoMinerService) Status() AutoMinerStatus {
+	ams.mu.Lock()
+	defer ams.mu.Unlock()
+	return ams.status
+}
+
+// mine starts a mining job unless one is still running, a busy miner simply skips this trigger.
+func (ams *autoMinerService) mine(trigger string) {
+	ams.mu.Lock()
+	defer ams.mu.Unlock()
+
+	if !ams.status.Running {
+		return
+	}
+
+	for _, job := range ams.miningJobSvc.List() {
+		if job.Status == MiningJobRunning {
+			return
+		}
+	}
+
+	ams.status.LastTrigger = trigger
+	ams.status.LastTriggerAt = time.Now().Unix()
+
+	job, err := ams.miningJobSvc.Start("auto-mined", ams.status.Config.MinerAddress, -1, 0)
+	if err != nil {
+		ams.status.LastError = err.Error()
+		log.Println("Auto-miner:", err)
+		return
+	}
+
+	ams.status.JobsStarted++
+	ams.status.LastJobID = job.ID
+	ams.status.LastError = ""
+}
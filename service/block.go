@@ -1,97 +1,368 @@
 package service
 
 import (
-	redisPkg "blockchain-backend/infras/redis"
+	"blockchain-backend/config"
+	"blockchain-backend/infras/storage"
 	"blockchain-backend/util"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"runtime"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// BlockVersionLegacy blocks are hashed over concatenated strings, they are only accepted when
+	// ALLOW_LEGACY_BLOCKS is set
+	BlockVersionLegacy int64 = 0
+	// BlockVersionCanonical blocks and their transactions are hashed over util.Encoder preimages
+	BlockVersionCanonical int64 = 1
+	// BlockVersionTarget proof-of-work blocks must hash to at most the compact target Bits,
+	// older blocks must start with Difficulty zero bits
+	BlockVersionTarget int64 = 2
+
+	CurrentBlockVersion = BlockVersionTarget
 )
 
 type Block struct {
+	Version      int64         `json:"version"`
 	BlockNumber  int64         `json:"block_number"`
 	Hash         string        `json:"hash"`
 	Binary       string        `json:"binary"`
 	ParentHash   string        `json:"parent_hash"`
 	Nonce        int64         `json:"nonce"`
 	Difficulty   int64         `json:"difficulty"`
+	Bits         uint32        `json:"bits,omitempty"` // compact proof-of-work target
 	Timestamp    int64         `json:"timestamp"`
 	Miner        string        `json:"miner"`
+	MerkleRoot   string        `json:"merkle_root"`
 	Transactions []Transaction `json:"transactions"`
 	Data         string        `json:"data"`
+	Signature    string        `json:"signature,omitempty"`  // block producer signature over Hash, proof-of-authority and proof-of-stake only
+	ChainWork    string        `json:"chain_work,omitempty"` // hex total work of the chain up to this block
 }
 
 type IBlockService interface {
 	Genesis(nonce, difficulty int64) *Block
-	adjustDifficulty(originalBlock Block, timestamp int64) int64
-	NewBlock(lastBlock Block, transactions []Transaction, data, miner string, position int64) (*Block, error)
+	NextDifficulty(parents []Block) int64
+	NextBits(parents []Block) uint32
+	GetRetargetConfig() (interval, targetBlockTime int64)
+	NewBlock(ctx context.Context, parents []Block, transactions []Transaction, data, miner string, position int64, opts MiningOptions) (*Block, error)
+	Engine() ConsensusEngine
 	SetDifficulty(difficulty int64)
 	GetDifficulty() int64
+	SetWorkers(workers int)
+	GetWorkers() int
+	GetMiningStats() MiningStats
+	SubscribeMiningTrace() (<-chan MiningTraceEvent, func())
+	SetTraceSampleRate(rate int64)
+	GetTraceSampleRate() int64
 	HashBlock(block *Block, lastHash string) string
+	MerkleRoot(transactions []Transaction, version int64) string
+	TransactionLeaves(transactions []Transaction, version int64) []string
+	IsSupportedVersion(version int64) bool
+}
+
+// MiningOptions tunes a single proof-of-work search.
+type MiningOptions struct {
+	Workers  int // 0 uses the service default
+	Progress *MiningProgress
+}
+
+// MiningStats describes the last proof-of-work search, HashRate is in hashes per second.
+type MiningStats struct {
+	Workers    int     `json:"workers"`
+	Attempts   int64   `json:"attempts"`
+	DurationMs int64   `json:"duration_ms"`
+	HashRate   float64 `json:"hash_rate"`
 }
 
+const (
+	defaultTargetBlockTime int64 = 10
+	// maxRetargetFactor bounds how far a single retarget can move, like Bitcoin's factor of 4
+	maxRetargetFactor = 4
+	maxDifficulty     = 255
+)
+
 type blockService struct {
-	difficulty         int64
-	mineRate           int64
-	transactionPoolSvc ITransactionPoolService
+	difficulty        atomic.Int64
+	retargetInterval  int64
+	targetBlockTime   int64
+	allowLegacyBlocks bool
+	engine            ConsensusEngine
+	workers           atomic.Int64
+	statsMu           sync.RWMutex
+	lastStats         MiningStats
+	trace             *miningTrace
+	transactionSvc    ITransactionService
+	store             storage.IStorage
 }
 
-func NewBlockService(transactionPoolSvc ITransactionPoolService) IBlockService {
-	return &blockService{
-		difficulty:         10,
-		mineRate:           10000,
-		transactionPoolSvc: transactionPoolSvc,
+func NewBlockService(transactionSvc ITransactionService, store storage.IStorage) IBlockService {
+	workers := config.ConfigEnv.MiningWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	targetBlockTime := config.ConfigEnv.TargetBlockTime
+	if targetBlockTime <= 0 {
+		targetBlockTime = defaultTargetBlockTime
+	}
+
+	bs := &blockService{
+		retargetInterval:  config.ConfigEnv.RetargetInterval,
+		targetBlockTime:   targetBlockTime,
+		allowLegacyBlocks: config.ConfigEnv.AllowLegacyBlocks,
+		trace:             newMiningTrace(config.ConfigEnv.MiningTraceSampleRate),
+		transactionSvc:    transactionSvc,
+		store:             store,
 	}
+	bs.difficulty.Store(10)
+	bs.workers.Store(int64(workers))
+
+	engine, err := newConsensusEngine(bs, config.ConfigEnv.Consensus)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	bs.engine = engine
+
+	return bs
+}
+
+func (bs *blockService) Engine() ConsensusEngine {
+	return bs.engine
 }
 
 func (bs *blockService) SetDifficulty(difficulty int64) {
-	bs.difficulty = difficulty
-	redisPkg.RedisService.Set(redisPkg.DifficultyKey, strconv.FormatInt(difficulty, 10))
+	bs.difficulty.Store(difficulty)
+	bs.store.Set(storage.DifficultyKey, strconv.FormatInt(difficulty, 10))
 }
 
 func (bs *blockService) GetDifficulty() int64 {
-	return bs.difficulty
+	return bs.difficulty.Load()
+}
+
+func (bs *blockService) SetWorkers(workers int) {
+	bs.workers.Store(int64(workers))
 }
 
+func (bs *blockService) GetWorkers() int {
+	return int(bs.workers.Load())
+}
+
+func (bs *blockService) GetMiningStats() MiningStats {
+	bs.statsMu.RLock()
+	defer bs.statsMu.RUnlock()
+	return bs.lastStats
+}
+
+// HashBlock hashes the block header, the transactions are committed to through block.MerkleRoot.
 func (bs *blockService) HashBlock(block *Block, lastHash string) string {
-	transaction, _ := json.Marshal(block.Transactions)
+	return bs.hashBlock(block, lastHash).Hex()
+}
+
+func (bs *blockService) hashBlock(block *Block, lastHash string) common.Hash {
+	if block.Version == BlockVersionLegacy {
+		return bs.legacyHashBlock(block, lastHash)
+	}
+
+	encoder := util.NewEncoder("blab/block").
+		Int64(block.Version).
+		Int64(block.BlockNumber).
+		String(lastHash).
+		Int64(block.Nonce).
+		Int64(block.Difficulty)
+	if block.Version >= BlockVersionTarget {
+		encoder.Uint64(uint64(block.Bits))
+	}
+	encoder.
+		Int64(block.Timestamp).
+		String(block.Miner).
+		String(block.MerkleRoot).
+		String(block.Data)
+
+	return util.CryptoHash(encoder.Bytes())
+}
+
+// legacyHashBlock is the BlockVersionLegacy preimage, blocks mined before Merkle roots existed have an
+// empty root and are hashed over the raw transaction JSON instead.
+func (bs *blockService) legacyHashBlock(block *Block, lastHash string) common.Hash {
+	transactions := block.MerkleRoot
+	if transactions == "" {
+		transactionsJson, _ := json.Marshal(block.Transactions)
+		transactions = string(transactionsJson)
+	}
 
-	return util.CryptoHash([]byte(strconv.FormatInt(block.BlockNumber, 10) + lastHash + string(rune(block.Nonce)) + strconv.FormatInt(block.Difficulty, 10) + strconv.FormatInt(block.Timestamp, 10) + block.Miner + string(transaction) + block.Data)).Hex()
+	return util.CryptoHash([]byte(strconv.FormatInt(block.BlockNumber, 10) + lastHash + string(rune(block.Nonce)) + strconv.FormatInt(block.Difficulty, 10) + strconv.FormatInt(block.Timestamp, 10) + block.Miner + transactions + block.Data))
+}
+
+// IsSupportedVersion reports whether blocks of this version are accepted by this node.
+func (bs *blockService) IsSupportedVersion(version int64) bool {
+	if version == BlockVersionLegacy {
+		return bs.allowLegacyBlocks
+	}
+	return version == BlockVersionCanonical || version == BlockVersionTarget
+}
+
+// TransactionLeaves recomputes the hash of every transaction with the hashing of the block version,
+// so a tampered body changes its leaf even when the stored Hash field is left untouched.
+func (bs *blockService) TransactionLeaves(transactions []Transaction, version int64) []string {
+	leaves := make([]string, len(transactions))
+	for i := range transactions {
+		if version == BlockVersionLegacy {
+			leaves[i] = bs.transactionSvc.LegacyTxHash(&transactions[i])
+		} else {
+			leaves[i] = bs.transactionSvc.TxHash(&transactions[i])
+		}
+	}
+	return leaves
+}
+
+func (bs *blockService) MerkleRoot(transactions []Transaction, version int64) string {
+	return util.MerkleRoot(bs.TransactionLeaves(transactions, version))
 }
 
 func (bs *blockService) Genesis(nonce, difficulty int64) *Block {
 	return &Block{
+		Version:      CurrentBlockVersion,
 		BlockNumber:  1,
 		Hash:         "0x",
 		ParentHash:   "0x",
 		Nonce:        nonce,
 		Difficulty:   difficulty,
+		Bits:         util.BigToCompact(util.DifficultyToTarget(difficulty)),
 		Timestamp:    0,
 		Miner:        "0x",
 		Transactions: []Transaction{},
+		ChainWork:    hexutil.EncodeBig(new(big.Int)),
 	}
 }
 
-func (bs *blockService) adjustDifficulty(originalBlock Block, timestamp int64) int64 {
-	difficulty := originalBlock.Difficulty
+func (bs *blockService) GetRetargetConfig() (interval, targetBlockTime int64) {
+	return bs.retargetInterval, bs.targetBlockTime
+}
+
+// NextDifficulty returns the difficulty in leading zero bits required for a BlockVersionCanonical block mined
+// on top of parents, BlockVersionTarget blocks use NextBits.
+//
+// Without retargeting this is the difficulty set through SetDifficulty. With retargeting the difficulty
+// is inherited from the parent except on every retargetInterval-th block, where the time the last window
+// took is compared with the time it should have taken. Difficulty counts leading zero bits so the work
+// per block is 2^difficulty and the correction is log2(expected/actual), with actual clamped to a factor
+// of maxRetargetFactor either way.
+func (bs *blockService) NextDifficulty(parents []Block) int64 {
+	if bs.retargetInterval <= 0 || len(parents) == 0 {
+		return bs.difficulty.Load()
+	}
+
+	parent := parents[len(parents)-1]
+	height := parent.BlockNumber + 1
+	if (height-1)%bs.retargetInterval != 0 {
+		return parent.Difficulty
+	}
+
+	// the window spans the last retargetInterval block times, so it starts one block before them
+	first := max(len(parents)-1-int(bs.retargetInterval), 0)
+	// the genesis block has no real timestamp
+	if parents[first].Timestamp == 0 {
+		first++
+	}
+	if first >= len(parents)-1 {
+		return parent.Difficulty
+	}
+
+	span := parent.BlockNumber - parents[first].BlockNumber
+	expected := float64(span * bs.targetBlockTime)
+	actual := float64(parent.Timestamp - parents[first].Timestamp)
+	actual = math.Max(actual, expected/maxRetargetFactor)
+	actual = math.Min(actual, expected*maxRetargetFactor)
+
+	difficulty := parent.Difficulty + int64(math.Round(math.Log2(expected/actual)))
 	if difficulty < 1 {
 		return 1
 	}
+	if difficulty > maxDifficulty {
+		return maxDifficulty
+	}
+	return difficulty
+}
 
-	if timestamp-originalBlock.Timestamp > bs.mineRate {
-		return difficulty - 1
+// blockTarget is the proof-of-work target of block, blocks before BlockVersionTarget only have a Difficulty.
+func blockTarget(block *Block) *big.Int {
+	if block.Version >= BlockVersionTarget && block.Bits != 0 {
+		return util.CompactToBig(block.Bits)
 	}
+	return util.DifficultyToTarget(block.Difficulty)
+}
 
-	return difficulty + 1
+// NextBits returns the compact target required for the block mined on top of parents.
+//
+// It retargets at the same heights as NextDifficulty, but scales the parent target by actual/expected
+// so the difficulty moves by fractions instead of whole bits. The target stays between difficulty 1 and
+// maxDifficulty.
+func (bs *blockService) NextBits(parents []Block) uint32 {
+	return util.BigToCompact(bs.nextTarget(parents))
 }
 
-// NewBlock creates a new block, if position is -1, the block will be mined with all transactions
-func (bs *blockService) NewBlock(lastBlock Block, transactions []Transaction, data, miner string, position int64) (*Block, error) {
+func (bs *blockService) nextTarget(parents []Block) *big.Int {
+	if bs.retargetInterval <= 0 || len(parents) == 0 {
+		return util.DifficultyToTarget(bs.difficulty.Load())
+	}
+
+	parent := parents[len(parents)-1]
+	parentTarget := blockTarget(&parent)
+	height := parent.BlockNumber + 1
+	if (height-1)%bs.retargetInterval != 0 {
+		return parentTarget
+	}
+
+	// the window spans the last retargetInterval block times, so it starts one block before them
+	first := max(len(parents)-1-int(bs.retargetInterval), 0)
+	// the genesis block has no real timestamp
+	if parents[first].Timestamp == 0 {
+		first++
+	}
+	if first >= len(parents)-1 {
+		return parentTarget
+	}
+
+	span := parent.BlockNumber - parents[first].BlockNumber
+	expected := span * bs.targetBlockTime
+	actual := parent.Timestamp - parents[first].Timestamp
+	actual = max(actual, expected/maxRetargetFactor, 1)
+	actual = min(actual, expected*maxRetargetFactor)
+
+	target := new(big.Int).Mul(parentTarget, big.NewInt(actual))
+	target.Div(target, big.NewInt(expected))
+
+	if easiest := util.DifficultyToTarget(1); target.Cmp(easiest) > 0 {
+		target = easiest
+	}
+	if hardest := util.DifficultyToTarget(maxDifficulty); target.Cmp(hardest) < 0 {
+		target = hardest
+	}
+	return target
+}
+
+// NewBlock seals a block with the given transactions at position through the consensus engine, if position is -1
+// the block is appended after lastBlock. Sealing stops with ctx.Err() when ctx is cancelled.
+func (bs *blockService) NewBlock(ctx context.Context, parents []Block, transactions []Transaction, data, miner string, position int64, opts MiningOptions) (*Block, error) {
 	if len(transactions) == 0 {
 		return nil, fmt.Errorf("no transactions to mine")
 	}
+	if len(parents) == 0 {
+		return nil, fmt.Errorf("no parent block to mine on")
+	}
+	lastBlock := parents[len(parents)-1]
 
 	if position == -1 {
 		position = lastBlock.BlockNumber + 1
@@ -100,68 +371,179 @@ func (bs *blockService) NewBlock(lastBlock Block, transactions []Transaction, da
 		position = 1
 	}
 
-	lastHash := lastBlock.Hash
-	blockNumber := position
-	difficulty := bs.difficulty
-	nonce := 0
-	var timestamp int64 = 0
-	var blockHash string = "0x"
-	var binary string
-
 	newBlock := &Block{
-		BlockNumber:  blockNumber,
-		Hash:         blockHash,
-		Binary:       binary,
-		ParentHash:   lastHash,
-		Nonce:        int64(nonce),
-		Difficulty:   difficulty,
-		Timestamp:    timestamp,
+		Version:      CurrentBlockVersion,
+		BlockNumber:  position,
+		Hash:         "0x",
+		ParentHash:   lastBlock.Hash,
 		Miner:        miner,
+		MerkleRoot:   bs.MerkleRoot(transactions, CurrentBlockVersion),
 		Transactions: transactions,
 		Data:         data,
 	}
 
-	for {
-		nonce += 1
-		timestamp = time.Now().Unix() // time in seconds
-		//difficulty = bs.adjustDifficulty(lastBlock, timestamp)
-		newBlock.Timestamp = timestamp
-		newBlock.Nonce = int64(nonce)
+	bs.engine.Prepare(parents, newBlock)
+	if err := bs.engine.Seal(ctx, parents, newBlock, opts); err != nil {
+		return nil, err
+	}
+	newBlock.ChainWork = util.AddWork(lastBlock.ChainWork, bs.engine.Work(newBlock))
+
+	// log json block
+	blockJson, _ := json.Marshal(newBlock)
+	fmt.Println(string(blockJson))
+
+	return newBlock, nil
+}
+
+// mine searches for a nonce whose hash meets the target of the block and fills in Nonce, Timestamp, Hash and Binary.
+// The nonce space is split between workers by stride: worker i tries i+1, i+1+workers, i+1+2*workers, ...
+// and every worker stops as soon as one of them finds a valid hash.
+func (bs *blockService) mine(ctx context.Context, block *Block, opts MiningOptions) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = bs.GetWorkers()
+	}
+	target := blockTarget(block)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var attempts atomic.Int64
+	found := make(chan Block, 1)
+	startedAt := time.Now()
+	opts.Progress.start(workers)
+
+	var bestZeros atomic.Int64
+	if bs.trace.active() {
+		bs.trace.publish(MiningTraceEvent{
+			Type:        MiningTraceStarted,
+			BlockNumber: block.BlockNumber,
+			Difficulty:  block.Difficulty,
+			Target:      hexutil.EncodeBig(target),
+			Workers:     workers,
+			SampleRate:  bs.GetTraceSampleRate(),
+		})
+	}
 
-		blockHash = bs.HashBlock(newBlock, lastHash)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int, nonce int64) {
+			defer wg.Done()
 
-		binary, _ = util.HexToBin(blockHash)
+			candidate := *block
+			for ; ctx.Err() == nil; nonce += int64(workers) {
+				candidate.Timestamp = time.Now().Unix() // time in seconds
+				candidate.Nonce = nonce
 
-		if binary[:difficulty] == strings.Repeat("0", int(difficulty)) {
+				blockHash := bs.hashBlock(&candidate, candidate.ParentHash)
+				attempt := attempts.Add(1)
+				opts.Progress.update(blockHash)
+				if bs.trace.active() {
+					bs.traceAttempt(&candidate, worker, blockHash, attempt, &bestZeros)
+				}
+
+				if util.MeetsTarget(blockHash, target) {
+					candidate.Hash = blockHash.Hex()
+					candidate.Binary, _ = util.HexToBin(candidate.Hash)
+					select {
+					case found <- candidate:
+						cancel()
+					default:
+					}
+					return
+				}
+			}
+		}(i, int64(i+1))
+	}
+	wg.Wait()
+	opts.Progress.stop()
+
+	duration := time.Since(startedAt)
+	stats := MiningStats{
+		Workers:    workers,
+		Attempts:   attempts.Load(),
+		DurationMs: duration.Milliseconds(),
+	}
+	if duration > 0 {
+		stats.HashRate = float64(stats.Attempts) / duration.Seconds()
+	}
+	bs.statsMu.Lock()
+	bs.lastStats = stats
+	bs.statsMu.Unlock()
+
+	select {
+	case minedBlock := <-found:
+		*block = minedBlock
+		if bs.trace.active() {
+			bs.trace.publish(MiningTraceEvent{
+				Type:        MiningTraceFound,
+				BlockNumber: block.BlockNumber,
+				Difficulty:  block.Difficulty,
+				Nonce:       block.Nonce,
+				Hash:        block.Hash,
+				Binary:      block.Binary,
+				Zeros:       util.LeadingZeroBits(common.HexToHash(block.Hash)),
+				BestZeros:   int(bestZeros.Load()),
+				Attempts:    stats.Attempts,
+				DurationMs:  stats.DurationMs,
+			})
+		}
+		return nil
+	default:
+		err := context.Cause(ctx)
+		if bs.trace.active() {
+			bs.trace.publish(MiningTraceEvent{
+				Type:        MiningTraceStopped,
+				BlockNumber: block.BlockNumber,
+				BestZeros:   int(bestZeros.Load()),
+				Attempts:    stats.Attempts,
+				DurationMs:  stats.DurationMs,
+				Error:       err.Error(),
+			})
+		}
+		return err
+	}
+}
+
+// traceAttempt records the leading zeros of an attempt and publishes every sampled one.
+func (bs *blockService) traceAttempt(candidate *Block, worker int, hash common.Hash, attempt int64, bestZeros *atomic.Int64) {
+	zeros := int64(util.LeadingZeroBits(hash))
+	for {
+		best := bestZeros.Load()
+		if zeros <= best || bestZeros.CompareAndSwap(best, zeros) {
 			break
 		}
-		nonce++
+	}
 
+	if !bs.trace.sampled(attempt) {
+		return
 	}
-	newBlock.Hash = blockHash
-	newBlock.Binary = binary
 
-	bs.transactionPoolSvc.Clear()
+	binary, _ := util.HexToBin(hash.Hex())
+	bs.trace.publish(MiningTraceEvent{
+		Type:        MiningTraceAttempt,
+		BlockNumber: candidate.BlockNumber,
+		Worker:      worker,
+		Nonce:       candidate.Nonce,
+		Hash:        hash.Hex(),
+		Binary:      binary,
+		Zeros:       int(zeros),
+		BestZeros:   int(bestZeros.Load()),
+		Attempts:    attempt,
+	})
+}
 
-	// log json block
-	blockJson, _ := json.Marshal(newBlock)
-	fmt.Println(string(blockJson))
+// SubscribeMiningTrace streams the proof-of-work searches of this node until the returned func is called.
+func (bs *blockService) SubscribeMiningTrace() (<-chan MiningTraceEvent, func()) {
+	return bs.trace.subscribe()
+}
 
-	// sync to redis
-	//chain := Chain{}
-	//blockChain := redis.RedisService.Get(redis.ChainKey)
-	//if blockChain != "" {
-	//	err := json.Unmarshal([]byte(blockChain), &chain)
-	//	if err != nil {
-	//		return Block{}, err
-	//	}
-	//}
-	//
-	//chain.Blocks = append(chain.Blocks, *newBlock)
-	//blockChainBytes, _ := json.Marshal(chain)
-	//redis.RedisService.Set(redis.ChainKey, string(blockChainBytes))
-
-	//redis.RedisService.Publish(redis.ChannelSyncNodeKey, string(blockChainBytes))
+// SetTraceSampleRate traces one attempt in every rate hashes.
+func (bs *blockService) SetTraceSampleRate(rate int64) {
+	bs.trace.sampleRate.Store(rate)
+}
 
-	return newBlock, nil
+func (bs *blockService) GetTraceSampleRate() int64 {
+	return bs.trace.sampleRate.Load()
 }
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"blockchain-backend/infras/storage"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// IBlockStore keeps every block in the storage under its hash, the canonical chain under its block numbers and
+// the hash of the canonical head under HeadKey. BLOCK_HASHES lists every stored block so side branches
+// can be loaded back. Nodes sharing one storage keep the blocks by hash in a pool they all read.
+type IBlockStore interface {
+	PutBlock(block Block)
+	SetHead(blocks []Block, from int, oldLength int)
+	Head() (Block, bool, error)
+	BlockByNumber(blockNumber int64) (Block, error)
+	BlockByHash(hash string) (Block, error)
+	Hashes() []string
+	Clear()
+}
+
+type blockStore struct {
+	store  storage.IStorage
+	blocks storage.IStorage
+	shared bool
+}
+
+func NewBlockStore(store storage.IStorage) IBlockStore {
+	return &blockStore{
+		store:  store,
+		blocks: store,
+	}
+}
+
+// NewSharedBlockStore keeps the index of the node in store and the blocks by hash in blocks, which other nodes
+// read as well. Clear leaves the blocks, a block never changes under its hash and other nodes may need it.
+// Genesis blocks all have the hash 0x, so the genesis block stays in store.
+func NewSharedBlockStore(store storage.IStorage, blocks storage.IStorage) IBlockStore {
+	return &blockStore{
+		store:  store,
+		blocks: blocks,
+		shared: true,
+	}
+}
+
+func blockKey(hash string) string {
+	return storage.BlockKeyPrefix + hash
+}
+
+func blockNumberKey(blockNumber int64) string {
+	return storage.BlockNumberKeyPrefix + strconv.FormatInt(blockNumber, 10)
+}
+
+// PutBlock stores block under its hash, a block with the same hash is overwritten.
+func (bs *blockStore) PutBlock(block Block) {
+	blockBytes, _ := json.Marshal(block)
+	if bs.shared && block.BlockNumber != 1 {
+		bs.blocks.Set(blockKey(block.Hash), string(blockBytes))
+	} else {
+		bs.store.Set(blockKey(block.Hash), string(blockBytes))
+	}
+	bs.store.SetSet(storage.BlockHashesKey, block.Hash)
+}
+
+// SetHead points the block numbers of blocks[from:] at their hashes and moves the head to the last block in
+// one transaction, the numbers of a longer old chain of oldLength blocks are removed. The blocks must be stored.
+func (bs *blockStore) SetHead(blocks []Block, from int, oldLength int) {
+	values := make(map[string]string)
+	for _, block := range blocks[from:] {
+		values[blockNumberKey(block.BlockNumber)] = block.Hash
+	}
+
+	var deleted []string
+	if len(blocks) == 0 {
+		deleted = append(deleted, storage.HeadKey)
+	} else {
+		values[storage.HeadKey] = blocks[len(blocks)-1].Hash
+	}
+	for blockNumber := int64(len(blocks)) + 1; blockNumber <= int64(oldLength); blockNumber++ {
+		deleted = append(deleted, blockNumberKey(blockNumber))
+	}
+
+	bs.store.SetAtomic(values, deleted)
+}
+
+// Head is the canonical head, false when nothing is stored.
+func (bs *blockStore) Head() (Block, bool, error) {
+	hash := bs.store.Get(storage.HeadKey)
+	if hash == "" {
+		return Block{}, false, nil
+	}
+
+	block, err := bs.BlockByHash(hash)
+	if err != nil {
+		return Block{}, false, err
+	}
+	return block, true, nil
+}
+
+// BlockByNumber is the canonical block blockNumber.
+func (bs *blockStore) BlockByNumber(blockNumber int64) (Block, error) {
+	hash := bs.store.Get(blockNumberKey(blockNumber))
+	if hash == "" {
+		return Block{}, fmt.Errorf("block %d is not stored", blockNumber)
+	}
+	return bs.BlockByHash(hash)
+}
+
+func (bs *blockStore) BlockByHash(hash string) (Block, error) {
+	stored := bs.store.Get(blockKey(hash))
+	if stored == "" && bs.shared {
+		stored = bs.blocks.Get(blockKey(hash))
+	}
+	if stored == "" {
+		return Block{}, fmt.Errorf("block %s is not stored", hash)
+	}
+
+	var block Block
+	if err := json.Unmarshal([]byte(stored), &block); err != nil {
+		return Block{}, fmt.Errorf("decode block %s: %w", hash, err)
+	}
+	return block, nil
+}
+
+func (bs *blockStore) Hashes() []string {
+	return bs.store.GetSet(storage.BlockHashesKey)
+}
+
+// Clear removes every stored block, the block number index and the head.
+func (bs *blockStore) Clear() {
+	keys := []string{storage.HeadKey, storage.BlockHashesKey}
+	for _, hash := range bs.Hashes() {
+		keys = append(keys, blockKey(hash))
+	}
+	if head, ok, _ := bs.Head(); ok {
+		for blockNumber := int64(1); blockNumber <= head.BlockNumber; blockNumber++ {
+			keys = append(keys, blockNumberKey(blockNumber))
+		}
+	}
+
+	bs.store.Del(keys...)
+}
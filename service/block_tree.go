@@ -0,0 +1,435 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxReorgEvents bounds the reorg history kept in memory
+const maxReorgEvents = 100
+
+type ReorgReason string
+
+const (
+	ReorgMined        ReorgReason = "mined"
+	ReorgReplaceChain ReorgReason = "replace_chain"
+	ReorgRemine       ReorgReason = "remine"
+	ReorgImport       ReorgReason = "import"
+	ReorgPeer         ReorgReason = "peer"
+)
+
+// TreeBlock is a block of the block tree with the total work of the branch ending at it.
+type TreeBlock struct {
+	Block     Block  `json:"block"`
+	TotalWork string `json:"total_work"`
+	Canonical bool   `json:"canonical"`
+	Children  int    `json:"children"`
+}
+
+// Branch is the path from the genesis block to Tip, ForkHeight is the last height it shares with the canonical chain.
+type Branch struct {
+	Tip        string  `json:"tip"`
+	ForkHeight int64   `json:"fork_height"`
+	TotalWork  string  `json:"total_work"`
+	Blocks     []Block `json:"blocks"`
+}
+
+// ReorgEvent records a switch of the canonical head that rolled back blocks.
+type ReorgEvent struct {
+	Reason               ReorgReason `json:"reason"`
+	Timestamp            int64       `json:"timestamp"`
+	OldHead              string      `json:"old_head"`
+	NewHead              string      `json:"new_head"`
+	CommonAncestorHeight int64       `json:"common_ancestor_height"`
+	RolledBack           []string    `json:"rolled_back"`
+	Applied              []string    `json:"applied"`
+	RestoredTransactions int         `json:"restored_transactions"`
+	RemovedTransactions  int         `json:"removed_transactions"`
+}
+
+type treeNode struct {
+	block    Block
+	work     *big.Int // total work from the genesis block
+	children []string
+	order    int64 // insertion order, the earlier tip wins a tie
+}
+
+// blockTree keeps every block this node has seen keyed by hash, each block hangs off its parent so
+// competing blocks at the same height become side branches instead of overwriting each other.
+type blockTree struct {
+	nodes     map[string]*treeNode
+	genesis   string
+	head      string
+	nextOrder int64
+	reorgs    []ReorgEvent
+}
+
+func newBlockTree(genesis Block) *blockTree {
+	genesis.ChainWork = hexutil.EncodeBig(new(big.Int))
+	return &blockTree{
+		nodes: map[string]*treeNode{
+			genesis.Hash: {block: genesis, work: new(big.Int)},
+		},
+		genesis:   genesis.Hash,
+		head:      genesis.Hash,
+		nextOrder: 1,
+	}
+}
+
+func (bt *blockTree) has(hash string) bool {
+	_, ok := bt.nodes[hash]
+	return ok
+}
+
+// add hangs block off its parent, work is what the block adds to its branch and ChainWork is recomputed from it.
+// A block already in the tree is kept.
+func (bt *blockTree) add(block Block, work *big.Int) error {
+	if bt.has(block.Hash) {
+		return nil
+	}
+
+	parent, ok := bt.nodes[block.ParentHash]
+	if !ok {
+		return fmt.Errorf("parent %s of block %d is not in the block tree", block.ParentHash, block.BlockNumber)
+	}
+	if block.BlockNumber != parent.block.BlockNumber+1 {
+		return fmt.Errorf("block %d does not follow its parent %d", block.BlockNumber, parent.block.BlockNumber)
+	}
+
+	total := new(big.Int).Add(parent.work, work)
+	block.ChainWork = hexutil.EncodeBig(total)
+	bt.nodes[block.Hash] = &treeNode{
+		block: block,
+		work:  total,
+		order: bt.nextOrder,
+	}
+	bt.nextOrder++
+	parent.children = append(parent.children, block.Hash)
+	return nil
+}
+
+// heavier reports whether the branch ending at hash has more work than the branch ending at the head.
+func (bt *blockTree) heavier(hash string) bool {
+	return bt.nodes[hash].work.Cmp(bt.nodes[bt.head].work) > 0
+}
+
+// path is the branch from the genesis block to hash.
+func (bt *blockTree) path(hash string) []Block {
+	var blocks []Block
+	for node, ok := bt.nodes[hash]; ok; node, ok = bt.nodes[node.block.ParentHash] {
+		blocks = append(blocks, node.block)
+		if node.block.Hash == bt.genesis {
+			break
+		}
+	}
+
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+func (bt *blockTree) canonicalSet() map[string]bool {
+	canonical := make(map[string]bool)
+	for _, block := range bt.path(bt.head) {
+		canonical[block.Hash] = true
+	}
+	return canonical
+}
+
+func (bt *blockTree) view(node *treeNode, canonical map[string]bool) TreeBlock {
+	return TreeBlock{
+		Block:     node.block,
+		TotalWork: hexutil.EncodeBig(node.work),
+		Canonical: canonical[node.block.Hash],
+		Children:  len(node.children),
+	}
+}
+
+// tips are the blocks without children, the heaviest first.
+func (bt *blockTree) tips() []TreeBlock {
+	var nodes []*treeNode
+	for _, node := range bt.nodes {
+		if len(node.children) == 0 {
+			nodes = append(nodes, node)
+		}
+	}
+	sortByWork(nodes)
+
+	canonical := bt.canonicalSet()
+	tips := make([]TreeBlock, len(nodes))
+	for i, node := range nodes {
+		tips[i] = bt.view(node, canonical)
+	}
+	return tips
+}
+
+// orphans are the blocks off the canonical chain, lowest first.
+func (bt *blockTree) orphans() []TreeBlock {
+	canonical := bt.canonicalSet()
+	var nodes []*treeNode
+	for hash, node := range bt.nodes {
+		if !canonical[hash] {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].block.BlockNumber != nodes[j].block.BlockNumber {
+			return nodes[i].block.BlockNumber < nodes[j].block.BlockNumber
+		}
+		return nodes[i].order < nodes[j].order
+	})
+
+	orphans := make([]TreeBlock, len(nodes))
+	for i, node := range nodes {
+		orphans[i] = bt.view(node, canonical)
+	}
+	return orphans
+}
+
+func (bt *blockTree) branch(hash string) (Branch, error) {
+	node, ok := bt.nodes[hash]
+	if !ok {
+		return Branch{}, fmt.Errorf("block not found")
+	}
+
+	blocks := bt.path(hash)
+	canonical := bt.canonicalSet()
+	var forkHeight int64
+	for _, block := range blocks {
+		if !canonical[block.Hash] {
+			break
+		}
+		forkHeight = block.BlockNumber
+	}
+
+	return Branch{
+		Tip:        hash,
+		ForkHeight: forkHeight,
+		TotalWork:  hexutil.EncodeBig(node.work),
+		Blocks:     blocks,
+	}, nil
+}
+
+func (bt *blockTree) recordReorg(event ReorgEvent) {
+	bt.reorgs = append(bt.reorgs, event)
+	if len(bt.reorgs) > maxReorgEvents {
+		bt.reorgs = bt.reorgs[len(bt.reorgs)-maxReorgEvents:]
+	}
+}
+
+func sortByWork(nodes []*treeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if cmp := nodes[i].work.Cmp(nodes[j].work); cmp != 0 {
+			return cmp > 0
+		}
+		return nodes[i].order < nodes[j].order
+	})
+}
+
+func blockHashes(blocks []Block) []string {
+	hashes := make([]string, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash
+	}
+	return hashes
+}
+
+// rebuildTree starts the block tree over from the canonical chain, side branches are dropped.
+func (bls *blockchainService) rebuildTree() {
+	if len(bls.chain.Blocks) == 0 {
+		bls.tree = nil
+		return
+	}
+
+	bls.tree = newBlockTree(bls.chain.Blocks[0])
+	for i := 1; i < len(bls.chain.Blocks); i++ {
+		block := bls.chain.Blocks[i]
+		if err := bls.tree.add(block, bls.blockService.Engine().Work(&block)); err != nil {
+			log.Println("Block tree only follows the chain up to block", bls.chain.Blocks[i-1].BlockNumber, ":", err)
+			break
+		}
+		bls.tree.head = block.Hash
+	}
+}
+
+// loadTree rebuilds the block tree from the canonical chain and hangs the other stored blocks back onto it
+// as side branches.
+func (bls *blockchainService) loadTree() {
+	bls.rebuildTree()
+	if bls.tree == nil {
+		return
+	}
+
+	var side []Block
+	for _, hash := range bls.blockStore.Hashes() {
+		if bls.tree.has(hash) {
+			continue
+		}
+		block, err := bls.blockStore.BlockByHash(hash)
+		if err != nil {
+			log.Println("Discarding side block", hash, ":", err)
+			continue
+		}
+		side = append(side, block)
+	}
+
+	sort.SliceStable(side, func(i, j int) bool {
+		return side[i].BlockNumber < side[j].BlockNumber
+	})
+	for _, block := range side {
+		if err := bls.tree.add(block, bls.blockService.Engine().Work(&block)); err != nil {
+			log.Println("Discarding side block", block.Hash, ":", err)
+		}
+	}
+}
+
+// rewriteStore replaces every stored block with the canonical chain, for when the block tree starts over.
+func (bls *blockchainService) rewriteStore() {
+	bls.blockStore.Clear()
+	for _, block := range bls.chain.Blocks {
+		bls.blockStore.PutBlock(block)
+	}
+	bls.blockStore.SetHead(bls.chain.Blocks, 0, 0)
+}
+
+// addToTree adds block to the tree and stores it the first time it is seen.
+func (bls *blockchainService) addToTree(block Block) error {
+	if bls.tree.has(block.Hash) {
+		return nil
+	}
+
+	if err := bls.tree.add(block, bls.blockService.Engine().Work(&block)); err != nil {
+		return err
+	}
+	bls.blockStore.PutBlock(bls.tree.nodes[block.Hash].block)
+	return nil
+}
+
+// addBranch adds the blocks of a chain that the tree does not have yet, blocks[0] must be the genesis block of the tree.
+func (bls *blockchainService) addBranch(blocks []Block) error {
+	if bls.tree == nil || len(blocks) == 0 || blocks[0].Hash != bls.tree.genesis {
+		return fmt.Errorf("chain does not start at the genesis block of the block tree")
+	}
+
+	for i := 1; i < len(blocks); i++ {
+		if err := bls.addToTree(blocks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertBlock hangs block off its parent and makes it the canonical head when its branch is heavier than the
+// current head, otherwise it stays on a side branch. The caller holds bls.mu.
+func (bls *blockchainService) insertBlock(block Block, reason ReorgReason) error {
+	if bls.tree == nil {
+		return fmt.Errorf("the chain has no genesis block")
+	}
+
+	if err := bls.addToTree(block); err != nil {
+		return err
+	}
+
+	if bls.tree.heavier(block.Hash) {
+		bls.setHead(block.Hash, reason)
+		return nil
+	}
+
+	log.Println("Block", block.BlockNumber, block.Hash, "is on a side branch")
+	return nil
+}
+
+// setHead makes the branch ending at hash the canonical chain. The pool transactions of the blocks it rolls back
+// go back to the pool and the transactions of the blocks it applies leave it. A switch that rolls back blocks is
+// recorded as a reorg. The caller holds bls.mu.
+func (bls *blockchainService) setHead(hash string, reason ReorgReason) ReorgEvent {
+	old := bls.chain.Blocks
+	blocks := bls.tree.path(hash)
+	ancestor := commonAncestor(old, blocks)
+	orphaned, mined := reorgTransactions(old, blocks, ancestor)
+
+	pool := bls.transactionPoolService.GetTransactionPool()
+	var removed int
+	for _, minedHash := range mined {
+		if _, ok := pool[minedHash]; ok {
+			removed++
+		}
+	}
+
+	event := ReorgEvent{
+		Reason:               reason,
+		Timestamp:            time.Now().Unix(),
+		NewHead:              hash,
+		RolledBack:           blockHashes(old[ancestor+1:]),
+		Applied:              blockHashes(blocks[ancestor+1:]),
+		RestoredTransactions: len(orphaned),
+		RemovedTransactions:  removed,
+	}
+	if len(old) > 0 {
+		event.OldHead = old[len(old)-1].Hash
+	}
+	if ancestor >= 0 {
+		event.CommonAncestorHeight = blocks[ancestor].BlockNumber
+	}
+	if len(event.RolledBack) > 0 {
+		log.Println("Reorganized from", event.OldHead, "to", event.NewHead, "rolling back", len(event.RolledBack), "blocks")
+		bls.tree.recordReorg(event)
+	}
+
+	bls.tree.head = hash
+	bls.chain = Chain{Blocks: blocks}
+	bls.blockStore.SetHead(blocks, ancestor+1, len(old))
+
+	bls.transactionPoolService.Remove(mined...)
+	for i := range orphaned {
+		bls.transactionPoolService.SetTransaction(&orphaned[i])
+	}
+
+	return event
+}
+
+func (bls *blockchainService) GetTips() []TreeBlock {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	if bls.tree == nil {
+		return []TreeBlock{}
+	}
+	return bls.tree.tips()
+}
+
+func (bls *blockchainService) GetBranch(hash string) (Branch, error) {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	if bls.tree == nil {
+		return Branch{}, fmt.Errorf("block not found")
+	}
+	return bls.tree.branch(hash)
+}
+
+func (bls *blockchainService) GetOrphans() []TreeBlock {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	if bls.tree == nil {
+		return []TreeBlock{}
+	}
+	return bls.tree.orphans()
+}
+
+func (bls *blockchainService) GetReorgs() []ReorgEvent {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	if bls.tree == nil {
+		return []ReorgEvent{}
+	}
+	return append([]ReorgEvent{}, bls.tree.reorgs...)
+}
@@ -1,14 +1,19 @@
 package service
 
 import (
-	redisPkg "blockchain-backend/infras/redis"
+	"blockchain-backend/config"
+	"blockchain-backend/infras/storage"
 	"blockchain-backend/util"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"io"
 	"log"
-	"strconv"
+	"math/big"
 	"strings"
+	"sync"
 )
 
 type State string
@@ -18,53 +23,133 @@ const (
 	Invalid State = "INVALID"
 )
 
+// Chain is the canonical chain, the branch of the block tree that ends at its head.
 type Chain struct {
 	Blocks []Block `json:"blocks"`
 	//State            State   `json:"state"`
 	//BlockNumberValid int64   `json:"block_number_valid"`
 }
 
+// MerkleProof proves that the transaction TxHash is included in the block BlockNumber.
+type MerkleProof struct {
+	BlockNumber int64                  `json:"block_number"`
+	MerkleRoot  string                 `json:"merkle_root"`
+	TxHash      string                 `json:"tx_hash"`
+	Index       int                    `json:"index"`
+	Proof       []util.MerkleProofStep `json:"proof"`
+}
+
 type IBlockchainService interface {
 	Reset()
 	GetBlocks() Chain
 	GetBlock(blockNumber int64) (Block, error)
 	//AddBlock(block Block)
-	IsValidChain(chain Chain) (bool, int64)
-	IsValidTransactionData(chain Chain) bool
-	ReplaceChain(chain Chain) error
+	ValidateChain(chain Chain) error
+	AuditChain(chain Chain) ChainAudit
+	ReplaceChain(chain Chain) (ChainComparison, error)
+	ExportChain(w io.Writer, format ExportFormat, from, to int64) error
+	ImportChain(r io.Reader, format ExportFormat) (ImportResult, error)
 	BlockLength() int
 	GetTransactionHistory(address string) []Transaction
 	GetTransaction(transactionHash string) (Transaction, error)
+	GetMerkleProof(transactionHash string) (MerkleProof, error)
 	//SyncNode(pubsub *redis.PubSub)
-	ReplaceBlock(block *Block)
-	NewBlock(data, miner string, position int64) (*Block, error)
+	ReplaceBlock(block *Block) error
+	AcceptBlock(block Block) error
+	AcceptBranch(block Block, fetch func(hash string) (Block, error)) error
+	AcceptTransaction(transaction Transaction) error
+	GetBlockByHash(hash string) (Block, error)
+	OnNewBlock(listener func(block Block))
+	GetTips() []TreeBlock
+	GetBranch(hash string) (Branch, error)
+	GetOrphans() []TreeBlock
+	GetReorgs() []ReorgEvent
+	NewBlock(ctx context.Context, data, miner string, position int64, opts MiningOptions) (*Block, error)
+	Migrate() error
+	SetBlockPolicy(policy BlockPolicy) error
+	GetBlockPolicy() BlockPolicy
+	GetRewardInfo() RewardInfo
+	GetValidators() ValidatorSet
+	RemineFrom(ctx context.Context, blockNumber int64, data string, workers int, onEvent func(RemineEvent)) error
+	GetSnapshots() []SnapshotInfo
+	DiffSnapshot(id int64) (SnapshotDiff, error)
+	RestoreSnapshot(id int64) (SnapshotInfo, error)
 }
 
 type blockchainService struct {
+	mu                     sync.RWMutex
 	chain                  Chain
+	tree                   *blockTree
+	policy                 BlockPolicy
+	rewardSchedule         RewardSchedule
 	blockService           IBlockService
+	transactionService     ITransactionService
 	transactionPoolService ITransactionPoolService
+	blockStore             IBlockStore
+	store                  storage.IStorage
+	history                *chainHistory
+	blockListeners         []func(block Block)
 }
 
-func NewBlockchainService(blockService IBlockService, transactionPoolService ITransactionPoolService, chain Chain) IBlockchainService {
+func NewBlockchainService(blockService IBlockService, transactionService ITransactionService, transactionPoolService ITransactionPoolService, blockStore IBlockStore, store storage.IStorage, chain Chain) IBlockchainService {
 	if len(chain.Blocks) == 0 {
 		chain = Chain{
 			Blocks: []Block{},
 			//State:            Valid,
 			//BlockNumberValid: 0,
 		}
-		blockChainBytes, _ := json.Marshal(chain)
-		redisPkg.RedisService.Set(redisPkg.ChainKey, string(blockChainBytes))
 	}
 
-	return &blockchainService{
+	policy := BlockPolicy{
+		MaxTransactions: config.ConfigEnv.MaxBlockTransactions,
+		MaxBytes:        config.ConfigEnv.MaxBlockBytes,
+		Strategy:        TxSelectionStrategy(config.ConfigEnv.TxSelectionStrategy),
+	}
+	if policy.Strategy == "" {
+		policy.Strategy = OldestFirst
+	}
+	if err := policy.Validate(); err != nil {
+		log.Fatalln(err)
+	}
+
+	bls := &blockchainService{
 		chain:                  chain,
+		policy:                 policy,
+		rewardSchedule:         NewRewardSchedule(),
 		blockService:           blockService,
+		transactionService:     transactionService,
 		transactionPoolService: transactionPoolService,
+		blockStore:             blockStore,
+		store:                  store,
+		history:                newChainHistory(store),
 	}
+	bls.rebuildTree()
+
+	return bls
+}
+
+func (bls *blockchainService) SetBlockPolicy(policy BlockPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+	bls.policy = policy
+	return nil
+}
+
+func (bls *blockchainService) GetBlockPolicy() BlockPolicy {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+	return bls.policy
 }
 
-func (bls *blockchainService) NewBlock(data, miner string, position int64) (*Block, error) {
+// NewBlock mines the miner reward plus the pool transactions picked by the block policy on top of the block
+// before position and commits the result through ReplaceBlock. The mined transactions leave the pool once the
+// block is on the canonical chain, nothing is committed when mining fails or ctx is cancelled.
+func (bls *blockchainService) NewBlock(ctx context.Context, data, miner string, position int64, opts MiningOptions) (*Block, error) {
+	bls.mu.RLock()
 	var lastBlockNumber int64 = position - 2
 	if position == -1 {
 		lastBlockNumber = int64(len(bls.chain.Blocks) - 1)
@@ -72,54 +157,186 @@ func (bls *blockchainService) NewBlock(data, miner string, position int64) (*Blo
 	if position == 1 {
 		lastBlockNumber = 0
 	}
-	lastBlock := bls.chain.Blocks[lastBlockNumber]
-	block, err := bls.blockService.NewBlock(lastBlock, bls.transactionPoolService.GetTransactions(), data, miner, position)
+	if lastBlockNumber < 0 || lastBlockNumber >= int64(len(bls.chain.Blocks)) {
+		bls.mu.RUnlock()
+		return nil, fmt.Errorf("parent block for position %d not found", position)
+	}
+	parents := append([]Block{}, bls.chain.Blocks[:lastBlockNumber+1]...)
+	policy := bls.policy
+	bls.mu.RUnlock()
+
+	rewards, faucet := chainIssuance(parents)
+	reward := bls.rewardSchedule.RewardAt(parents[len(parents)-1].BlockNumber+1, rewards+faucet)
+	candidates := withinFaucet(bls.transactionPoolService.GetTransactions(), bls.rewardSchedule.FaucetAt(reward, rewards+faucet))
+
+	// the coinbase has a fixed size so its final value does not change what fits
+	selected := policy.Select(candidates, *bls.transactionService.RewardTransaction(miner, reward))
+	rewardTransaction := bls.transactionService.RewardTransaction(miner, reward+totalFees(selected))
+	transactions := append([]Transaction{*rewardTransaction}, selected...)
+
+	block, err := bls.blockService.NewBlock(ctx, parents, transactions, data, miner, position, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	bls.ReplaceBlock(block)
+	if err := bls.ReplaceBlock(block); err != nil {
+		return nil, err
+	}
+	bls.notifyBlock(*block)
+
 	return block, nil
 }
 
-func (bls *blockchainService) ReplaceBlock(block *Block) {
-	blockNumber := block.BlockNumber
+// ReplaceBlock adds block to the block tree. A block on top of a non-tip parent starts a side branch instead
+// of overwriting the block at its height, and becomes canonical once its branch is the heaviest. A genesis
+// block starts the chain over, the blocks after the old genesis block and the side branches are dropped.
+func (bls *blockchainService) ReplaceBlock(block *Block) error {
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+
 	log.Println("blockNumber: ", block)
-	if blockNumber == 1 && len(bls.chain.Blocks) == 0 {
+	if block.BlockNumber == 1 {
+		bls.snapshot(SnapshotNewGenesisBlock)
 		bls.chain.Blocks = []Block{*block}
+		bls.rebuildTree()
+		bls.rewriteStore()
+		return nil
+	}
+
+	return bls.insertBlock(*block, ReorgMined)
+}
+
+// maxAncestorFetch is how many missing ancestors of a received block AcceptBranch fetches
+const maxAncestorFetch = 32
+
+// ErrUnknownParent is returned by AcceptBlock for a block whose parent this node has not seen yet.
+var ErrUnknownParent = errors.New("parent block is not in the block tree")
+
+// AcceptBlock validates a block received from another node against its branch and adds it to the block tree
+// like ReplaceBlock. A block the tree already has is ignored, the listeners only hear about new blocks.
+func (bls *blockchainService) AcceptBlock(block Block) error {
+	bls.mu.Lock()
+	if bls.tree == nil {
+		bls.mu.Unlock()
+		return fmt.Errorf("the chain has no genesis block")
+	}
+	if bls.tree.has(block.Hash) {
+		bls.mu.Unlock()
+		return nil
+	}
+	if !bls.tree.has(block.ParentHash) {
+		bls.mu.Unlock()
+		return fmt.Errorf("block %d %s: %w", block.BlockNumber, block.Hash, ErrUnknownParent)
+	}
+
+	parents, state := bls.replayBranch(block.ParentHash)
+	check := &chainCheck{}
+	if !bls.checkBlock(check, state, parents, &block) {
+		bls.mu.Unlock()
+		return check.firstError()
+	}
+	err := bls.insertBlock(block, ReorgPeer)
+	bls.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	bls.notifyBlock(block)
+	return nil
+}
+
+// AcceptBranch accepts block like AcceptBlock after the ancestors the block tree is missing, which are fetched by
+// hash. A block more than maxAncestorFetch blocks ahead of the tree returns ErrUnknownParent.
+func (bls *blockchainService) AcceptBranch(block Block, fetch func(hash string) (Block, error)) error {
+	err := bls.AcceptBlock(block)
+	if !errors.Is(err, ErrUnknownParent) {
+		return err
 	}
-	if blockNumber > int64(len(bls.chain.Blocks)) {
-		bls.chain.Blocks = append(bls.chain.Blocks, *block)
-	} else {
-		for i, b := range bls.chain.Blocks {
-			if b.BlockNumber == blockNumber {
-				bls.chain.Blocks[i] = *block
-				break
+
+	missing := []Block{block}
+	for len(missing) <= maxAncestorFetch {
+		parentHash := missing[len(missing)-1].ParentHash
+		if _, err := bls.GetBlockByHash(parentHash); err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				if err := bls.AcceptBlock(missing[i]); err != nil {
+					return err
+				}
 			}
+			return nil
+		}
+
+		parent, err := fetch(parentHash)
+		if err != nil {
+			return fmt.Errorf("fetch block %s: %w", parentHash, err)
 		}
+		if parent.Hash != parentHash {
+			return fmt.Errorf("fetched block %s for %s", parent.Hash, parentHash)
+		}
+		missing = append(missing, parent)
+	}
+
+	return fmt.Errorf("block %d is more than %d blocks ahead: %w", block.BlockNumber, maxAncestorFetch, ErrUnknownParent)
+}
+
+// AcceptTransaction adds a transaction received from another node to the pool unless it is known or already mined.
+func (bls *blockchainService) AcceptTransaction(transaction Transaction) error {
+	if _, ok := bls.transactionPoolService.GetTransactionPool()[transaction.Hash]; ok {
+		return nil
+	}
+	if _, err := bls.GetTransaction(transaction.Hash); err == nil {
+		return nil
+	}
+	if err := bls.transactionService.VerifyTransaction(&transaction); err != nil {
+		return err
+	}
+
+	bls.transactionPoolService.SetTransaction(&transaction)
+	return nil
+}
+
+// OnNewBlock calls listener with every block this node mines or accepts from another node.
+func (bls *blockchainService) OnNewBlock(listener func(block Block)) {
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+	bls.blockListeners = append(bls.blockListeners, listener)
+}
 
+func (bls *blockchainService) notifyBlock(block Block) {
+	bls.mu.RLock()
+	listeners := bls.blockListeners
+	bls.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(block)
 	}
 }
 
 func (bls *blockchainService) Reset() {
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+
+	bls.snapshot(SnapshotReset)
 	bls.chain = Chain{
 		Blocks: []Block{*bls.blockService.Genesis(0, bls.blockService.GetDifficulty())},
 	}
-
-	blockChainBytes, _ := json.Marshal(bls.chain)
-	redisPkg.RedisService.Set(redisPkg.ChainKey, string(blockChainBytes))
-
-	redisPkg.RedisService.Publish(redisPkg.ChannelSyncNodeKey, string(blockChainBytes))
+	bls.rebuildTree()
+	bls.rewriteStore()
 
 	// clear transaction pool
 	bls.transactionPoolService.Clear()
 }
 
 func (bls *blockchainService) GetBlocks() Chain {
-	return bls.chain
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	return Chain{Blocks: append([]Block{}, bls.chain.Blocks...)}
 }
 
 func (bls *blockchainService) GetBlock(blockNumber int64) (Block, error) {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
 	for _, block := range bls.chain.Blocks {
 		if block.BlockNumber == blockNumber {
 			return block, nil
@@ -129,89 +346,140 @@ func (bls *blockchainService) GetBlock(blockNumber int64) (Block, error) {
 	return Block{}, fmt.Errorf("block not found")
 }
 
+// GetBlockByHash is any block of the block tree, side branches included.
+func (bls *blockchainService) GetBlockByHash(hash string) (Block, error) {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	if bls.tree == nil || !bls.tree.has(hash) {
+		return Block{}, fmt.Errorf("block not found")
+	}
+	return bls.tree.nodes[hash].block, nil
+}
+
 //func (bls *blockchainService) AddBlock(block Block) {
 //	bls.chain.Blocks = append(bls.chain.Blocks, block)
 //}
 
-func (bls *blockchainService) IsValidChain(chain Chain) (bool, int64) {
-	//if !reflect.DeepEqual(chain.Blocks[0], bls.blockService.Genesis()) {
-	//	log.Println("Genesis block is invalid")
-	//	return false
-	//}
-
-	for i := 1; i < len(chain.Blocks); i++ {
-		lastHash := chain.Blocks[i-1].Hash
-		//lastDifficulty := chain.Blocks[i-1].Difficulty
-		if lastHash != chain.Blocks[i].ParentHash {
-			return false, chain.Blocks[i].BlockNumber
-		}
+func (bls *blockchainService) GetRewardInfo() RewardInfo {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
 
-		block := chain.Blocks[i]
+	issued, faucet := chainIssuance(bls.chain.Blocks)
+	var height int64 = 1
+	if len(bls.chain.Blocks) > 0 {
+		height = bls.chain.Blocks[len(bls.chain.Blocks)-1].BlockNumber
+	}
 
-		transaction, _ := json.Marshal(block.Transactions)
+	return RewardInfo{
+		Schedule:          bls.rewardSchedule,
+		Height:            height,
+		CurrentReward:     bls.rewardSchedule.RewardAt(height+1, issued+faucet),
+		NextHalvingHeight: bls.rewardSchedule.NextHalvingHeight(height + 1),
+		IssuedRewards:     issued,
+		FaucetSupply:      faucet,
+		CirculatingSupply: issued + faucet,
+	}
+}
 
-		validHash := util.CryptoHash([]byte(strconv.FormatInt(block.BlockNumber, 10) + lastHash + string(rune(block.Nonce)) + strconv.FormatInt(block.Difficulty, 10) + strconv.FormatInt(block.Timestamp, 10) + block.Miner + string(transaction) + block.Data)).Hex()
-		if strings.Compare(validHash, chain.Blocks[i].Hash) != 0 {
-			log.Println("Invalid hash at block ", i)
-			return false, chain.Blocks[i].BlockNumber
-		}
+func (bls *blockchainService) GetValidators() ValidatorSet {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
 
-		//if math.Abs(float64(lastDifficulty-difficulty)) > 1 {
-		//	log.Println("Invalid difficulty at block ", i)
-		//	return false, chain.Blocks[i].BlockNumber
-		//}
+	stakes := chainStakes(bls.chain.Blocks)
+	var total int64
+	for _, stake := range stakes {
+		total += stake
 	}
 
-	return true, int64(len(chain.Blocks))
+	return ValidatorSet{
+		Stakes:       stakes,
+		TotalStake:   total,
+		NextProposer: Proposer(bls.chain.Blocks),
+	}
 }
 
-func (bls *blockchainService) IsValidTransactionData(chain Chain) bool {
-	for i := 1; i < len(chain.Blocks); i++ {
-		rewardTransactionCount := 0
-		for _, transaction := range chain.Blocks[i].Transactions {
-			if strings.Compare(transaction.From, common.Address{}.Hex()) == 0 {
-				rewardTransactionCount += 1
-				if rewardTransactionCount > 1 {
-					log.Fatalln("Miner rewards exceed limit")
-					return false
-				}
+// fillChainWork recomputes the ChainWork of every block, the genesis block carries no work.
+func (bls *blockchainService) fillChainWork(blocks []Block) {
+	for i := range blocks {
+		if i == 0 {
+			blocks[i].ChainWork = hexutil.EncodeBig(new(big.Int))
+			continue
+		}
+		blocks[i].ChainWork = util.AddWork(blocks[i-1].ChainWork, bls.blockService.Engine().Work(&blocks[i]))
+	}
+}
 
-				if transaction.Value != util.MinersReward {
-					log.Fatalln("Miner reward amount is invalid")
-					return false
-				}
-			} else {
+// totalFees sums the fees paid by regular transactions, faucet grants from the zero address pay none.
+func totalFees(transactions []Transaction) int64 {
+	var fees int64
+	for _, transaction := range transactions {
+		if strings.Compare(transaction.From, common.Address{}.Hex()) != 0 {
+			fees += transaction.Fee
+		}
+	}
+	return fees
+}
 
-			}
+// ReplaceChain switches to chain when it is valid and carries more work than the local chain, the heaviest chain
+// wins even when it is shorter. The pool transactions of the local blocks the switch orphans go back to the pool
+// and the transactions the new chain mined leave it. A lighter chain that shares the genesis block is kept as
+// a side branch of the block tree.
+func (bls *blockchainService) ReplaceChain(chain Chain) (ChainComparison, error) {
+	if len(chain.Blocks) == 0 {
+		return ChainComparison{}, fmt.Errorf("received chain is empty")
+	}
 
-		}
+	if err := bls.ValidateChain(chain); err != nil {
+		return ChainComparison{}, fmt.Errorf("received chain is invalid: %w", err)
 	}
 
-	return true
-}
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
 
-func (bls *blockchainService) ReplaceChain(chain Chain) error {
-	if len(chain.Blocks) <= len(bls.chain.Blocks) {
-		return fmt.Errorf("received chain is not longer than the current chain")
+	comparison, ancestor := bls.compareChains(bls.chain.Blocks, chain.Blocks)
+	localWork, _ := hexutil.DecodeBig(comparison.LocalWork)
+	candidateWork, _ := hexutil.DecodeBig(comparison.CandidateWork)
+	if candidateWork.Cmp(localWork) <= 0 {
+		if ancestor >= 0 {
+			_ = bls.addBranch(chain.Blocks)
+		}
+		return comparison, fmt.Errorf("received chain does not have more work than the current chain")
+	}
+	// a chain that only extends the local chain destroys nothing, routine syncs do not fill the history
+	if ancestor < len(bls.chain.Blocks)-1 {
+		bls.snapshot(SnapshotReplaceChain)
 	}
 
-	if isValid, _ := bls.IsValidChain(chain); !isValid {
-		return fmt.Errorf("received chain is invalid")
+	// a node without a chain starts its block tree from the received genesis block
+	if ancestor < 0 || bls.addBranch(chain.Blocks) != nil {
+		bls.blockStore.Clear()
+		bls.blockStore.PutBlock(chain.Blocks[0])
+		bls.tree = newBlockTree(chain.Blocks[0])
+		if err := bls.addBranch(chain.Blocks); err != nil {
+			return comparison, err
+		}
 	}
-	bls.chain = chain
 
-	blockChainBytes, _ := json.Marshal(chain)
-	redisPkg.RedisService.Set(redisPkg.ChainKey, string(blockChainBytes))
+	event := bls.setHead(chain.Blocks[len(chain.Blocks)-1].Hash, ReorgReplaceChain)
+	comparison.Replaced = true
+	comparison.OrphanedBlocks = len(event.RolledBack)
+	comparison.RestoredTransactions = event.RestoredTransactions
 
-	//redisPkg.RedisService.Publish(redisPkg.ChannelSyncNodeKey, string(blockChainBytes))
-	return nil
+	return comparison, nil
 }
 
 func (bls *blockchainService) BlockLength() int {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
 	return len(bls.chain.Blocks)
 }
 
 func (bls *blockchainService) GetTransactionHistory(address string) []Transaction {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
 	var transactions []Transaction
 	for _, block := range bls.chain.Blocks {
 		for _, transaction := range block.Transactions {
@@ -225,6 +493,9 @@ func (bls *blockchainService) GetTransactionHistory(address string) []Transactio
 }
 
 func (bls *blockchainService) GetTransaction(transactionHash string) (Transaction, error) {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
 	for _, block := range bls.chain.Blocks {
 		for _, transaction := range block.Transactions {
 			if strings.Compare(transaction.Hash, transactionHash) == 0 {
@@ -235,45 +506,34 @@ func (bls *blockchainService) GetTransaction(transactionHash string) (Transactio
 	return Transaction{}, fmt.Errorf("transaction not found")
 }
 
-//func (bls *blockchainService) SyncNode(pubsub *redis.PubSub) {
-//	defer func(pubsub *redis.PubSub) {
-//		err := pubsub.Close()
-//		if err != nil {
-//			log.Fatalln(err)
-//		}
-//	}(pubsub)
-//
-//	for {
-//		msg, err := pubsub.ReceiveMessage(redisPkg.Ctx)
-//		if err != nil {
-//			log.Fatalln(err)
-//		}
-//		log.Println("Received message: ", msg.Payload, " from channel: ", msg.Channel)
-//
-//		if strings.Compare(msg.Channel, redisPkg.ChannelSyncNodeKey) == 0 {
-//
-//			var chain Chain
-//			err = json.Unmarshal([]byte(msg.Payload), &chain)
-//			if err != nil {
-//				log.Fatalln(err)
-//			}
-//
-//			err = bls.ReplaceChain(chain)
-//			if err != nil {
-//				log.Fatalln(err)
-//			}
-//		}
-//
-//		if strings.Compare(msg.Channel, redisPkg.ChannelSyncTransactionKey) == 0 {
-//
-//			var transaction Transaction
-//			err = json.Unmarshal([]byte(msg.Payload), &transaction)
-//			if err != nil {
-//				log.Fatalln(err)
-//			}
-//
-//			bls.transactionPoolService.SetTransaction(transaction)
-//		}
-//	}
-//
-//}
+func (bls *blockchainService) GetMerkleProof(transactionHash string) (MerkleProof, error) {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	for _, block := range bls.chain.Blocks {
+		for i, transaction := range block.Transactions {
+			if strings.Compare(transaction.Hash, transactionHash) != 0 {
+				continue
+			}
+
+			if block.MerkleRoot == "" {
+				return MerkleProof{}, fmt.Errorf("block %d was mined without a merkle root", block.BlockNumber)
+			}
+
+			leaves := bls.blockService.TransactionLeaves(block.Transactions, block.Version)
+			proof, err := util.MerkleProof(leaves, i)
+			if err != nil {
+				return MerkleProof{}, err
+			}
+
+			return MerkleProof{
+				BlockNumber: block.BlockNumber,
+				MerkleRoot:  block.MerkleRoot,
+				TxHash:      leaves[i],
+				Index:       i,
+				Proof:       proof,
+			}, nil
+		}
+	}
+	return MerkleProof{}, fmt.Errorf("transaction not found")
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"blockchain-backend/config"
+	"blockchain-backend/infras/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type SnapshotOperation string
+
+const (
+	SnapshotReset           SnapshotOperation = "reset"
+	SnapshotNewGenesisBlock SnapshotOperation = "new_genesis_block"
+	SnapshotReplaceChain    SnapshotOperation = "replace_chain"
+	SnapshotRestore         SnapshotOperation = "restore"
+	SnapshotRemine          SnapshotOperation = "remine"
+)
+
+// defaultHistorySize is how many snapshots are kept when CHAIN_HISTORY_SIZE is 0
+const defaultHistorySize = 10
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotInfo describes the chain and pool an operation was about to destroy.
+type SnapshotInfo struct {
+	ID           int64             `json:"id"`
+	Operation    SnapshotOperation `json:"operation"`
+	CreatedAt    int64             `json:"created_at"`
+	Height       int64             `json:"height"`
+	Head         string            `json:"head"`
+	Transactions int               `json:"transactions"`
+}
+
+type ChainSnapshot struct {
+	SnapshotInfo
+	Blocks []Block       `json:"blocks"`
+	Pool   []Transaction `json:"pool"`
+}
+
+// BlockRef names a block of a diff.
+type BlockRef struct {
+	BlockNumber int64  `json:"block_number"`
+	Hash        string `json:"hash"`
+}
+
+// SnapshotDiff compares a snapshot with the current chain. Both share their first CommonBlocks blocks,
+// SnapshotBlocks and ChainBlocks are the blocks after them, SnapshotPool and ChainPool the pool transactions
+// only one of them holds.
+type SnapshotDiff struct {
+	Snapshot       SnapshotInfo `json:"snapshot"`
+	CommonBlocks   int          `json:"common_blocks"`
+	SnapshotBlocks []BlockRef   `json:"snapshot_blocks"`
+	ChainBlocks    []BlockRef   `json:"chain_blocks"`
+	SnapshotPool   []string     `json:"snapshot_pool"`
+	ChainPool      []string     `json:"chain_pool"`
+}
+
+// chainHistory keeps the last snapshots under SNAPSHOT:<id> and lists them, oldest first, under SnapshotsKey.
+type chainHistory struct {
+	store storage.IStorage
+	size  int
+}
+
+type snapshotIndex struct {
+	NextID    int64          `json:"next_id"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+}
+
+func newChainHistory(store storage.IStorage) *chainHistory {
+	size := config.ConfigEnv.ChainHistorySize
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	return &chainHistory{
+		store: store,
+		size:  size,
+	}
+}
+
+func snapshotKey(id int64) string {
+	return storage.SnapshotKeyPrefix + strconv.FormatInt(id, 10)
+}
+
+func (ch *chainHistory) index() snapshotIndex {
+	index := snapshotIndex{NextID: 1}
+	if value := ch.store.Get(storage.SnapshotsKey); value != "" {
+		_ = json.Unmarshal([]byte(value), &index)
+	}
+	return index
+}
+
+// save keeps blocks and pool as the newest snapshot and drops the oldest ones beyond the size of the history.
+func (ch *chainHistory) save(operation SnapshotOperation, blocks []Block, pool []Transaction) SnapshotInfo {
+	index := ch.index()
+
+	snapshot := ChainSnapshot{
+		SnapshotInfo: SnapshotInfo{
+			ID:           index.NextID,
+			Operation:    operation,
+			CreatedAt:    time.Now().Unix(),
+			Transactions: len(pool),
+		},
+		Blocks: blocks,
+		Pool:   pool,
+	}
+	if len(blocks) > 0 {
+		head := blocks[len(blocks)-1]
+		snapshot.Height, snapshot.Head = head.BlockNumber, head.Hash
+	}
+
+	index.NextID++
+	index.Snapshots = append(index.Snapshots, snapshot.SnapshotInfo)
+	var dropped []string
+	for len(index.Snapshots) > ch.size {
+		dropped = append(dropped, snapshotKey(index.Snapshots[0].ID))
+		index.Snapshots = index.Snapshots[1:]
+	}
+
+	snapshotBytes, _ := json.Marshal(snapshot)
+	indexBytes, _ := json.Marshal(index)
+	ch.store.SetAtomic(map[string]string{
+		snapshotKey(snapshot.ID): string(snapshotBytes),
+		storage.SnapshotsKey:     string(indexBytes),
+	}, dropped)
+	return snapshot.SnapshotInfo
+}
+
+// list is the newest snapshot first.
+func (ch *chainHistory) list() []SnapshotInfo {
+	snapshots := ch.index().Snapshots
+	if snapshots == nil {
+		snapshots = []SnapshotInfo{}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID > snapshots[j].ID
+	})
+	return snapshots
+}
+
+func (ch *chainHistory) get(id int64) (ChainSnapshot, error) {
+	value := ch.store.Get(snapshotKey(id))
+	if value == "" {
+		return ChainSnapshot{}, ErrSnapshotNotFound
+	}
+
+	var snapshot ChainSnapshot
+	if err := json.Unmarshal([]byte(value), &snapshot); err != nil {
+		return ChainSnapshot{}, fmt.Errorf("snapshot %d is corrupt: %w", id, err)
+	}
+	return snapshot, nil
+}
+
+// snapshot saves the chain and pool before operation replaces them, an empty chain has nothing to undo.
+// The caller holds bls.mu.
+func (bls *blockchainService) snapshot(operation SnapshotOperation) {
+	if len(bls.chain.Blocks) == 0 {
+		return
+	}
+	blocks := append([]Block{}, bls.chain.Blocks...)
+	bls.history.save(operation, blocks, bls.transactionPoolService.GetTransactions())
+}
+
+func (bls *blockchainService) GetSnapshots() []SnapshotInfo {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	return bls.history.list()
+}
+
+func (bls *blockchainService) DiffSnapshot(id int64) (SnapshotDiff, error) {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	snapshot, err := bls.history.get(id)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+
+	diff := SnapshotDiff{
+		Snapshot:       snapshot.SnapshotInfo,
+		SnapshotBlocks: []BlockRef{},
+		ChainBlocks:    []BlockRef{},
+	}
+	for diff.CommonBlocks < min(len(snapshot.Blocks), len(bls.chain.Blocks)) &&
+		snapshot.Blocks[diff.CommonBlocks].Hash == bls.chain.Blocks[diff.CommonBlocks].Hash {
+		diff.CommonBlocks++
+	}
+	for _, block := range snapshot.Blocks[diff.CommonBlocks:] {
+		diff.SnapshotBlocks = append(diff.SnapshotBlocks, BlockRef{BlockNumber: block.BlockNumber, Hash: block.Hash})
+	}
+	for _, block := range bls.chain.Blocks[diff.CommonBlocks:] {
+		diff.ChainBlocks = append(diff.ChainBlocks, BlockRef{BlockNumber: block.BlockNumber, Hash: block.Hash})
+	}
+
+	pool := bls.transactionPoolService.GetTransactionPool()
+	snapshotPool := make(map[string]bool, len(snapshot.Pool))
+	diff.SnapshotPool, diff.ChainPool = []string{}, []string{}
+	for _, transaction := range snapshot.Pool {
+		snapshotPool[transaction.Hash] = true
+		if _, ok := pool[transaction.Hash]; !ok {
+			diff.SnapshotPool = append(diff.SnapshotPool, transaction.Hash)
+		}
+	}
+	for hash := range pool {
+		if !snapshotPool[hash] {
+			diff.ChainPool = append(diff.ChainPool, hash)
+		}
+	}
+	sort.Strings(diff.SnapshotPool)
+	sort.Strings(diff.ChainPool)
+
+	return diff, nil
+}
+
+// RestoreSnapshot brings back the chain and pool of a snapshot. The state it replaces is snapshotted first, so
+// a restore can be undone as well. Side branches of the block tree are dropped like on a reset.
+func (bls *blockchainService) RestoreSnapshot(id int64) (SnapshotInfo, error) {
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+
+	snapshot, err := bls.history.get(id)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	if len(snapshot.Blocks) == 0 {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %d has no blocks", id)
+	}
+
+	bls.snapshot(SnapshotRestore)
+
+	bls.chain = Chain{Blocks: snapshot.Blocks}
+	bls.rebuildTree()
+	bls.rewriteStore()
+
+	// the restored pool is local history, it is not announced to peers
+	bls.transactionPoolService.Replace(snapshot.Pool)
+
+	return snapshot.SnapshotInfo, nil
+}
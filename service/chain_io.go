@@ -0,0 +1,375 @@
+package service
+
+import (
+	"blockchain-backend/util"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type ExportFormat string
+
+const (
+	// NDJSON writes one JSON block per line
+	NDJSON ExportFormat = "ndjson"
+	// BinaryFormat writes binaryMagic and binaryFormatVersion followed by every block as a length-prefixed frame
+	BinaryFormat ExportFormat = "binary"
+)
+
+const (
+	binaryMagic         = "BLAB"
+	binaryFormatVersion = 1
+	// maxBlockFrame bounds the size of one binary block so a corrupt length cannot exhaust memory
+	maxBlockFrame = 64 << 20
+)
+
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch ExportFormat(format) {
+	case "", NDJSON:
+		return NDJSON, nil
+	case BinaryFormat:
+		return BinaryFormat, nil
+	default:
+		return "", fmt.Errorf("format must be one of %s, %s", NDJSON, BinaryFormat)
+	}
+}
+
+// ImportResult is how far an import got. LastValidBlock is the last block of the stream that is in the block
+// tree, an import that failed resumes after it.
+type ImportResult struct {
+	Imported       int                   `json:"imported"`
+	Skipped        int                   `json:"skipped"`
+	LastValidBlock int64                 `json:"last_valid_block"`
+	LastValidHash  string                `json:"last_valid_hash,omitempty"`
+	Head           string                `json:"head"`
+	Replaced       bool                  `json:"replaced"`
+	Error          *ChainValidationError `json:"error,omitempty"`
+}
+
+// ExportChain streams the canonical blocks from through to in format, to 0 is the head.
+func (bls *blockchainService) ExportChain(w io.Writer, format ExportFormat, from, to int64) error {
+	bls.mu.RLock()
+	blocks := bls.chain.Blocks
+	bls.mu.RUnlock()
+
+	if len(blocks) == 0 {
+		return fmt.Errorf("the chain has no blocks")
+	}
+	head := blocks[len(blocks)-1].BlockNumber
+	if from == 0 {
+		from = 1
+	}
+	if to == 0 {
+		to = head
+	}
+	if from < 1 || from > to || from > head {
+		return fmt.Errorf("block range %d-%d is outside the chain of %d blocks", from, to, head)
+	}
+	to = min(to, head)
+
+	writer := bufio.NewWriter(w)
+	encode := newBlockWriter(writer, format)
+	if format == BinaryFormat {
+		header := binary.BigEndian.AppendUint32([]byte(binaryMagic), binaryFormatVersion)
+		if _, err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	for _, block := range blocks[from-1 : to] {
+		if err := encode(block); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func newBlockWriter(w io.Writer, format ExportFormat) func(block Block) error {
+	if format == BinaryFormat {
+		return func(block Block) error {
+			frame := encodeBlock(block)
+			if _, err := w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(frame)))); err != nil {
+				return err
+			}
+			_, err := w.Write(frame)
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	return func(block Block) error {
+		return encoder.Encode(block)
+	}
+}
+
+// encodeBlock leaves out Binary, it is derived from the hash.
+func encodeBlock(block Block) []byte {
+	e := &util.Encoder{}
+	e.Int64(block.Version).
+		Int64(block.BlockNumber).
+		String(block.Hash).
+		String(block.ParentHash).
+		Int64(block.Nonce).
+		Int64(block.Difficulty).
+		Uint64(uint64(block.Bits)).
+		Int64(block.Timestamp).
+		String(block.Miner).
+		String(block.MerkleRoot).
+		String(block.Data).
+		String(block.Signature).
+		String(block.ChainWork).
+		Uint64(uint64(len(block.Transactions)))
+	for _, transaction := range block.Transactions {
+		e.String(transaction.Hash).
+			String(transaction.Signature).
+			String(string(transaction.Type)).
+			String(transaction.From).
+			String(transaction.To).
+			Int64(transaction.Value).
+			Int64(transaction.Fee).
+			String(transaction.Data).
+			Int64(transaction.Timestamp)
+	}
+	return e.Bytes()
+}
+
+func decodeBlock(frame []byte) (Block, error) {
+	d := util.NewDecoder(frame)
+	block := Block{
+		Version:     d.Int64(),
+		BlockNumber: d.Int64(),
+		Hash:        d.String(),
+		ParentHash:  d.String(),
+		Nonce:       d.Int64(),
+		Difficulty:  d.Int64(),
+		Bits:        uint32(d.Uint64()),
+		Timestamp:   d.Int64(),
+		Miner:       d.String(),
+		MerkleRoot:  d.String(),
+		Data:        d.String(),
+		Signature:   d.String(),
+		ChainWork:   d.String(),
+	}
+
+	count := d.Uint64()
+	// every transaction takes at least its fixed width fields, a larger count is corrupt
+	if count > uint64(d.Len()) {
+		return Block{}, util.ErrShortBuffer
+	}
+	block.Transactions = make([]Transaction, 0, count)
+	for i := uint64(0); i < count; i++ {
+		block.Transactions = append(block.Transactions, Transaction{
+			Hash:      d.String(),
+			Signature: d.String(),
+			Type:      TransactionType(d.String()),
+			From:      d.String(),
+			To:        d.String(),
+			Value:     d.Int64(),
+			Fee:       d.Int64(),
+			Data:      d.String(),
+			Timestamp: d.Int64(),
+		})
+	}
+
+	if d.Err() != nil {
+		return Block{}, d.Err()
+	}
+	if d.Len() != 0 {
+		return Block{}, fmt.Errorf("%d trailing bytes after block %d", d.Len(), block.BlockNumber)
+	}
+	if block.Hash != "0x" {
+		block.Binary, _ = util.HexToBin(block.Hash)
+	}
+	return block, nil
+}
+
+// newBlockReader returns the blocks of r one at a time and io.EOF after the last one. An empty format is
+// detected from the first bytes of r.
+func newBlockReader(r io.Reader, format ExportFormat) (func() (Block, error), error) {
+	reader := bufio.NewReader(r)
+	if format == "" {
+		format = NDJSON
+		if magic, _ := reader.Peek(len(binaryMagic)); bytes.Equal(magic, []byte(binaryMagic)) {
+			format = BinaryFormat
+		}
+	}
+
+	if format != BinaryFormat {
+		decoder := json.NewDecoder(reader)
+		return func() (Block, error) {
+			var block Block
+			err := decoder.Decode(&block)
+			return block, err
+		}, nil
+	}
+
+	header := make([]byte, len(binaryMagic)+4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("read binary header: %w", err)
+	}
+	if string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, fmt.Errorf("not a binary chain export")
+	}
+	if version := binary.BigEndian.Uint32(header[len(binaryMagic):]); version != binaryFormatVersion {
+		return nil, fmt.Errorf("unsupported binary format version %d", version)
+	}
+
+	return func() (Block, error) {
+		var length [4]byte
+		if _, err := io.ReadFull(reader, length[:]); err != nil {
+			return Block{}, err
+		}
+		size := binary.BigEndian.Uint32(length[:])
+		if size > maxBlockFrame {
+			return Block{}, fmt.Errorf("block frame of %d bytes is larger than %d", size, maxBlockFrame)
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return Block{}, io.ErrUnexpectedEOF
+		}
+		return decodeBlock(frame)
+	}, nil
+}
+
+// ImportChain reads a chain export block by block and adds every valid block to the block tree, the first block
+// of the stream is the genesis block or a block whose parent is in the tree. Blocks the tree already has are
+// skipped, so an import that failed resumes after its last valid block when it is run again. The valid blocks
+// before a failure are kept and the head moves to the imported branch when it has more work. Blocks are decoded
+// without holding the chain lock, so a slow stream does not stall mining and reads.
+func (bls *blockchainService) ImportChain(r io.Reader, format ExportFormat) (ImportResult, error) {
+	next, err := newBlockReader(r, format)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	imp := &chainImport{}
+	var failure error
+	for {
+		block, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			failure = fmt.Errorf("decode block after block %d: %w", imp.result.LastValidBlock, err)
+			break
+		}
+
+		bls.mu.Lock()
+		failure = bls.importBlock(imp, block)
+		bls.mu.Unlock()
+		if failure != nil {
+			break
+		}
+	}
+
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+
+	result := imp.result
+	if result.Imported > 0 && bls.tree != nil && bls.tree.has(result.LastValidHash) && bls.tree.heavier(result.LastValidHash) {
+		bls.setHead(result.LastValidHash, ReorgImport)
+		result.Replaced = true
+	}
+	if bls.tree != nil {
+		result.Head = bls.tree.head
+	}
+
+	return result, failure
+}
+
+// chainImport is the progress of ImportChain between blocks. parents and state belong to tree, they are
+// replayed again when the block tree was rebuilt in the meantime.
+type chainImport struct {
+	result  ImportResult
+	tree    *blockTree
+	parents []Block
+	state   *ledger
+}
+
+// importBlock adds the next block of an import. The caller holds bls.mu.
+func (bls *blockchainService) importBlock(imp *chainImport, block Block) error {
+	if block.BlockNumber == 1 {
+		created, err := bls.importGenesis(block)
+		if err != nil {
+			imp.result.Error = err
+			return err
+		}
+		if created {
+			imp.result.Imported++
+		} else {
+			imp.result.Skipped++
+		}
+		imp.tree, imp.parents, imp.state = bls.tree, bls.tree.path(bls.tree.genesis), newLedger()
+		imp.result.LastValidBlock, imp.result.LastValidHash = block.BlockNumber, bls.tree.genesis
+		return nil
+	}
+
+	if bls.tree == nil {
+		return fmt.Errorf("the chain has no genesis block, the import must start at block 1")
+	}
+
+	// the first block, a block of another branch or a block after a rebuild of the tree starts from its parent
+	if imp.tree != bls.tree || len(imp.parents) == 0 || block.ParentHash != imp.parents[len(imp.parents)-1].Hash {
+		if !bls.tree.has(block.ParentHash) {
+			return fmt.Errorf("parent %s of block %d is not in the block tree", block.ParentHash, block.BlockNumber)
+		}
+		imp.parents, imp.state = bls.replayBranch(block.ParentHash)
+		imp.tree = bls.tree
+	}
+
+	if bls.tree.has(block.Hash) {
+		bls.checkTransactions(&chainCheck{audit: true}, imp.state, &block)
+		imp.parents = append(imp.parents, bls.tree.nodes[block.Hash].block)
+		imp.result.Skipped++
+		imp.result.LastValidBlock, imp.result.LastValidHash = block.BlockNumber, block.Hash
+		return nil
+	}
+
+	check := &chainCheck{}
+	if !bls.checkBlock(check, imp.state, imp.parents, &block) {
+		imp.result.Error = check.firstError()
+		return imp.result.Error
+	}
+	if err := bls.addToTree(block); err != nil {
+		return err
+	}
+	imp.parents = append(imp.parents, bls.tree.nodes[block.Hash].block)
+	imp.result.Imported++
+	imp.result.LastValidBlock, imp.result.LastValidHash = block.BlockNumber, block.Hash
+	return nil
+}
+
+// importGenesis starts an empty node from genesis and reports true, otherwise genesis must match the genesis
+// block of this node. The caller holds bls.mu.
+func (bls *blockchainService) importGenesis(genesis Block) (bool, *ChainValidationError) {
+	var local *Block
+	if bls.tree != nil {
+		local = &bls.tree.nodes[bls.tree.genesis].block
+	}
+
+	check := &chainCheck{}
+	if !checkGenesis(check, &genesis, local) {
+		return false, check.firstError()
+	}
+
+	if bls.tree != nil {
+		return false, nil
+	}
+	bls.chain = Chain{Blocks: []Block{genesis}}
+	bls.rebuildTree()
+	bls.rewriteStore()
+	return true, nil
+}
+
+// replayBranch is the branch ending at hash and the ledger after its blocks. The caller holds bls.mu.
+func (bls *blockchainService) replayBranch(hash string) ([]Block, *ledger) {
+	blocks := bls.tree.path(hash)
+	state := newLedger()
+	for i := 1; i < len(blocks); i++ {
+		bls.checkTransactions(&chainCheck{audit: true}, state, &blocks[i])
+	}
+	return blocks, state
+}
@@ -0,0 +1,470 @@
+package service
+
+import (
+	"blockchain-backend/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxFutureBlockTime is how far ahead of this node's clock a block timestamp may be, like Bitcoin's two hours
+const maxFutureBlockTime = 2 * time.Hour
+
+type ChainErrorCode string
+
+const (
+	ErrEmptyChain           ChainErrorCode = "empty_chain"
+	ErrGenesisMismatch      ChainErrorCode = "genesis_mismatch"
+	ErrInvalidBlockNumber   ChainErrorCode = "invalid_block_number"
+	ErrInvalidParentHash    ChainErrorCode = "invalid_parent_hash"
+	ErrInvalidTimestamp     ChainErrorCode = "invalid_timestamp"
+	ErrUnsupportedVersion   ChainErrorCode = "unsupported_version"
+	ErrInvalidMerkleRoot    ChainErrorCode = "invalid_merkle_root"
+	ErrInvalidHash          ChainErrorCode = "invalid_hash"
+	ErrInsufficientWork     ChainErrorCode = "insufficient_work"
+	ErrInvalidDifficulty    ChainErrorCode = "invalid_difficulty"
+	ErrInvalidSignature     ChainErrorCode = "invalid_signature"
+	ErrInvalidHeader        ChainErrorCode = "invalid_header"
+	ErrInvalidChainWork     ChainErrorCode = "invalid_chain_work"
+	ErrInvalidCoinbase      ChainErrorCode = "invalid_coinbase"
+	ErrInvalidTransaction   ChainErrorCode = "invalid_transaction"
+	ErrDuplicateTransaction ChainErrorCode = "duplicate_transaction"
+	ErrInsufficientFunds    ChainErrorCode = "insufficient_funds"
+	ErrInvalidFaucet        ChainErrorCode = "invalid_faucet"
+)
+
+type Severity string
+
+const (
+	// SeverityError breaks a consensus rule, the chain is rejected
+	SeverityError Severity = "error"
+	// SeverityWarning is a wrong value this node recomputes on import, the chain is still accepted
+	SeverityWarning Severity = "warning"
+)
+
+// ChainValidationError is a consensus rule a chain breaks, TxHash is set for transaction rules. Expected
+// and Actual are filled in when the rule compares a value.
+type ChainValidationError struct {
+	Code        ChainErrorCode `json:"code"`
+	Severity    Severity       `json:"severity"`
+	BlockNumber int64          `json:"block_number"`
+	BlockHash   string         `json:"block_hash,omitempty"`
+	TxHash      string         `json:"tx_hash,omitempty"`
+	Message     string         `json:"message"`
+	Expected    string         `json:"expected,omitempty"`
+	Actual      string         `json:"actual,omitempty"`
+}
+
+func (e *ChainValidationError) Error() string {
+	if e.TxHash != "" {
+		return fmt.Sprintf("block %d transaction %s: %s", e.BlockNumber, e.TxHash, e.Message)
+	}
+	return fmt.Sprintf("block %d: %s", e.BlockNumber, e.Message)
+}
+
+// ruleError is what consensus engines and the transaction service return for a broken rule, so the
+// validator can report the code and the compared values.
+type ruleError struct {
+	code     ChainErrorCode
+	expected string
+	actual   string
+	message  string
+}
+
+func (e *ruleError) Error() string {
+	return e.message
+}
+
+func newRuleError(code ChainErrorCode, expected, actual string, format string, args ...any) *ruleError {
+	return &ruleError{
+		code:     code,
+		expected: expected,
+		actual:   actual,
+		message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// BlockAudit lists the violations of one block.
+type BlockAudit struct {
+	BlockNumber int64                  `json:"block_number"`
+	Hash        string                 `json:"hash"`
+	Violations  []ChainValidationError `json:"violations"`
+}
+
+// ChainAudit is every violation of a chain grouped by block, Blocks only holds blocks with violations.
+type ChainAudit struct {
+	Valid    bool         `json:"valid"`
+	Length   int          `json:"length"`
+	Errors   int          `json:"errors"`
+	Warnings int          `json:"warnings"`
+	Blocks   []BlockAudit `json:"blocks"`
+}
+
+// chainCheck collects the violations of a chain. Validation stops at the first error, an audit goes on.
+type chainCheck struct {
+	audit      bool
+	violations []ChainValidationError
+}
+
+// report records violation and reports whether the check goes on.
+func (cc *chainCheck) report(violation *ChainValidationError) bool {
+	if violation.Severity == "" {
+		violation.Severity = SeverityError
+	}
+	cc.violations = append(cc.violations, *violation)
+	return cc.audit || violation.Severity != SeverityError
+}
+
+func (cc *chainCheck) blockError(code ChainErrorCode, block *Block, expected, actual string, format string, args ...any) bool {
+	return cc.report(&ChainValidationError{
+		Code:        code,
+		BlockNumber: block.BlockNumber,
+		BlockHash:   block.Hash,
+		Message:     fmt.Sprintf(format, args...),
+		Expected:    expected,
+		Actual:      actual,
+	})
+}
+
+func (cc *chainCheck) transactionError(code ChainErrorCode, block *Block, transaction *Transaction, expected, actual string, format string, args ...any) bool {
+	return cc.report(&ChainValidationError{
+		Code:        code,
+		BlockNumber: block.BlockNumber,
+		BlockHash:   block.Hash,
+		TxHash:      transaction.Hash,
+		Message:     fmt.Sprintf(format, args...),
+		Expected:    expected,
+		Actual:      actual,
+	})
+}
+
+// ruleViolation turns an error of an engine or the transaction service into a violation, errors that are
+// not a ruleError get fallback as their code.
+func ruleViolation(err error, fallback ChainErrorCode) *ChainValidationError {
+	var rule *ruleError
+	if errors.As(err, &rule) {
+		return &ChainValidationError{Code: rule.code, Message: rule.message, Expected: rule.expected, Actual: rule.actual}
+	}
+	return &ChainValidationError{Code: fallback, Message: err.Error()}
+}
+
+// firstError is the first violation that rejects the chain.
+func (cc *chainCheck) firstError() *ChainValidationError {
+	for i := range cc.violations {
+		if cc.violations[i].Severity == SeverityError {
+			return &cc.violations[i]
+		}
+	}
+	return nil
+}
+
+// ValidateChain checks every consensus rule of chain against the genesis block of this node and returns
+// a *ChainValidationError for the first rule it breaks.
+func (bls *blockchainService) ValidateChain(chain Chain) error {
+	if err := bls.validateChain(chain, bls.localGenesis()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AuditChain checks chain like ValidateChain but walks the whole chain and reports every violation.
+func (bls *blockchainService) AuditChain(chain Chain) ChainAudit {
+	check := &chainCheck{audit: true}
+	bls.checkChain(check, chain, bls.localGenesis())
+
+	audit := ChainAudit{
+		Length: len(chain.Blocks),
+		Blocks: []BlockAudit{},
+	}
+	for _, violation := range check.violations {
+		if violation.Severity == SeverityError {
+			audit.Errors++
+		} else {
+			audit.Warnings++
+		}
+
+		// violations are reported block by block
+		if n := len(audit.Blocks); n == 0 || audit.Blocks[n-1].BlockNumber != violation.BlockNumber || audit.Blocks[n-1].Hash != violation.BlockHash {
+			audit.Blocks = append(audit.Blocks, BlockAudit{BlockNumber: violation.BlockNumber, Hash: violation.BlockHash})
+		}
+		last := &audit.Blocks[len(audit.Blocks)-1]
+		last.Violations = append(last.Violations, violation)
+	}
+	audit.Valid = audit.Errors == 0
+
+	return audit
+}
+
+func (bls *blockchainService) localGenesis() *Block {
+	bls.mu.RLock()
+	defer bls.mu.RUnlock()
+
+	if len(bls.chain.Blocks) == 0 {
+		return nil
+	}
+	genesis := bls.chain.Blocks[0]
+	return &genesis
+}
+
+// validateChain returns the first violation that rejects chain. A nil genesis accepts any well formed genesis block.
+func (bls *blockchainService) validateChain(chain Chain, genesis *Block) *ChainValidationError {
+	check := &chainCheck{}
+	bls.checkChain(check, chain, genesis)
+	return check.firstError()
+}
+
+// checkChain checks the header and then the transactions of every block of chain.
+func (bls *blockchainService) checkChain(check *chainCheck, chain Chain, genesis *Block) {
+	if len(chain.Blocks) == 0 {
+		check.report(&ChainValidationError{Code: ErrEmptyChain, Message: "chain has no blocks"})
+		return
+	}
+
+	if !checkGenesis(check, &chain.Blocks[0], genesis) {
+		return
+	}
+
+	ledger := newLedger()
+	for i := 1; i < len(chain.Blocks); i++ {
+		if !bls.checkBlock(check, ledger, chain.Blocks[:i], &chain.Blocks[i]) {
+			return
+		}
+	}
+}
+
+// checkBlock checks block on top of parents, which start at the genesis block, and applies its transactions
+// to ledger. ledger must hold the transactions of parents.
+func (bls *blockchainService) checkBlock(check *chainCheck, ledger *ledger, parents []Block, block *Block) bool {
+	if !bls.checkHeader(check, parents, block) {
+		return false
+	}
+	return bls.checkTransactions(check, ledger, block)
+}
+
+func checkGenesis(check *chainCheck, block, genesis *Block) bool {
+	if block.BlockNumber != 1 || block.Hash != "0x" || block.ParentHash != "0x" || len(block.Transactions) != 0 {
+		return check.blockError(ErrGenesisMismatch, block, "", "", "malformed genesis block")
+	}
+
+	if genesis == nil {
+		return true
+	}
+
+	fields := []struct {
+		name             string
+		expected, actual string
+	}{
+		{"version", strconv.FormatInt(genesis.Version, 10), strconv.FormatInt(block.Version, 10)},
+		{"nonce", strconv.FormatInt(genesis.Nonce, 10), strconv.FormatInt(block.Nonce, 10)},
+		{"difficulty", strconv.FormatInt(genesis.Difficulty, 10), strconv.FormatInt(block.Difficulty, 10)},
+		{"bits", fmt.Sprintf("%08x", genesis.Bits), fmt.Sprintf("%08x", block.Bits)},
+		{"timestamp", strconv.FormatInt(genesis.Timestamp, 10), strconv.FormatInt(block.Timestamp, 10)},
+		{"miner", genesis.Miner, block.Miner},
+	}
+	for _, field := range fields {
+		if field.expected != field.actual {
+			if !check.blockError(ErrGenesisMismatch, block, field.expected, field.actual, "genesis block %s does not match the genesis block of this node", field.name) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// checkHeader checks that block follows its parent and carries a valid proof for the consensus engine.
+// The engine checks the hash against the difficulty or target, or the signature of the block producer.
+func (bls *blockchainService) checkHeader(check *chainCheck, parents []Block, block *Block) bool {
+	latest := time.Now().Add(maxFutureBlockTime).Unix()
+	engine := bls.blockService.Engine()
+	parent := parents[len(parents)-1]
+
+	if expected := parent.BlockNumber + 1; block.BlockNumber != expected {
+		if !check.blockError(ErrInvalidBlockNumber, block, strconv.FormatInt(expected, 10), strconv.FormatInt(block.BlockNumber, 10), "block number does not follow parent block %d", parent.BlockNumber) {
+			return false
+		}
+	}
+
+	if block.ParentHash != parent.Hash {
+		if !check.blockError(ErrInvalidParentHash, block, parent.Hash, block.ParentHash, "parent hash is not the hash of block %d", parent.BlockNumber) {
+			return false
+		}
+	}
+
+	if block.Timestamp < parent.Timestamp {
+		if !check.blockError(ErrInvalidTimestamp, block, ">= "+strconv.FormatInt(parent.Timestamp, 10), strconv.FormatInt(block.Timestamp, 10), "timestamp is before the parent timestamp") {
+			return false
+		}
+	}
+	if block.Timestamp > latest {
+		if !check.blockError(ErrInvalidTimestamp, block, "<= "+strconv.FormatInt(latest, 10), strconv.FormatInt(block.Timestamp, 10), "timestamp is too far in the future") {
+			return false
+		}
+	}
+
+	if !bls.blockService.IsSupportedVersion(block.Version) || block.Version < parent.Version {
+		if !check.blockError(ErrUnsupportedVersion, block, "", strconv.FormatInt(block.Version, 10), "unsupported block version") {
+			return false
+		}
+	}
+
+	if block.MerkleRoot != "" || block.Version != BlockVersionLegacy {
+		if expected := bls.blockService.MerkleRoot(block.Transactions, block.Version); block.MerkleRoot != expected {
+			if !check.blockError(ErrInvalidMerkleRoot, block, expected, block.MerkleRoot, "merkle root does not match the transactions") {
+				return false
+			}
+		}
+	}
+
+	if err := engine.VerifyHeader(parents, block); err != nil {
+		violation := ruleViolation(err, ErrInvalidHeader)
+		violation.BlockNumber = block.BlockNumber
+		violation.BlockHash = block.Hash
+		if !check.report(violation) {
+			return false
+		}
+	}
+
+	// chain work is a cache of the work of the blocks that is recomputed on import
+	if block.ChainWork != "" {
+		if expected := util.AddWork(parent.ChainWork, engine.Work(block)); block.ChainWork != expected {
+			check.report(&ChainValidationError{
+				Code:        ErrInvalidChainWork,
+				Severity:    SeverityWarning,
+				BlockNumber: block.BlockNumber,
+				BlockHash:   block.Hash,
+				Message:     "chain work does not add up",
+				Expected:    expected,
+				Actual:      block.ChainWork,
+			})
+		}
+	}
+
+	return true
+}
+
+// ledger is the state a chain builds up block by block: balances, locked stakes, the mined transactions and the
+// issued block rewards.
+type ledger struct {
+	balances map[string]int64
+	stakes   map[string]int64
+	seen     map[string]bool
+	issued   int64
+}
+
+func newLedger() *ledger {
+	return &ledger{
+		balances: make(map[string]int64),
+		stakes:   make(map[string]int64),
+		seen:     make(map[string]bool),
+	}
+}
+
+// checkTransactions replays the transactions of block onto l. Every block starts with a coinbase paying the
+// scheduled block reward plus the fees of the block, other transactions from the zero address are wallet
+// faucet grants, each worth more than 0 and together within the faucet limit and the max supply. No transaction may appear twice in a block, as the merkle root pairs an odd leaf with itself
+// and a block repeating its last transactions has the same root. Signed transactions must be signed by their
+// sender, appear once in the chain, and leave the sender with a spendable balance of at least zero, stake
+// counts as locked.
+func (bls *blockchainService) checkTransactions(check *chainCheck, l *ledger, block *Block) bool {
+	zeroAddress := common.Address{}.Hex()
+
+	reward := bls.rewardSchedule.RewardAt(block.BlockNumber, l.issued)
+	faucet := bls.rewardSchedule.FaucetAt(reward, l.issued)
+	blockReward, blockFaucet := blockIssuance(*block)
+	l.issued += blockReward + blockFaucet
+
+	// legacy blocks predate fees, the reward schedule and checked signatures, they only move balances
+	legacy := block.Version == BlockVersionLegacy
+
+	if !legacy {
+		if len(block.Transactions) == 0 || strings.Compare(block.Transactions[0].From, zeroAddress) != 0 {
+			if !check.blockError(ErrInvalidCoinbase, block, "", "", "missing miner reward") {
+				return false
+			}
+		} else {
+			coinbase := block.Transactions[0]
+			if expected := reward + totalFees(block.Transactions[1:]); coinbase.Value != expected {
+				if !check.transactionError(ErrInvalidCoinbase, block, &coinbase, strconv.FormatInt(expected, 10), strconv.FormatInt(coinbase.Value, 10), "miner reward is not the block reward plus the fees") {
+					return false
+				}
+			}
+		}
+		if blockFaucet > faucet {
+			if !check.blockError(ErrInvalidFaucet, block, "<= "+strconv.FormatInt(faucet, 10), strconv.FormatInt(blockFaucet, 10), "faucet grants create more coins than allowed") {
+				return false
+			}
+		}
+	}
+
+	inBlock := make(map[string]bool, len(block.Transactions))
+	for j := range block.Transactions {
+		transaction := &block.Transactions[j]
+		signed := strings.Compare(transaction.From, zeroAddress) != 0
+
+		duplicate := inBlock[transaction.Hash]
+		if duplicate {
+			if !check.transactionError(ErrDuplicateTransaction, block, transaction, "", "", "transaction appears twice in the block") {
+				return false
+			}
+		}
+		inBlock[transaction.Hash] = true
+
+		if !signed && j > 0 && !legacy && transaction.Value <= 0 {
+			if !check.transactionError(ErrInvalidFaucet, block, transaction, "> 0", strconv.FormatInt(transaction.Value, 10), "faucet grant must be worth more than 0") {
+				return false
+			}
+		}
+
+		if signed && !legacy {
+			if err := bls.transactionService.VerifyTransaction(transaction); err != nil {
+				violation := ruleViolation(err, ErrInvalidTransaction)
+				violation.BlockNumber = block.BlockNumber
+				violation.BlockHash = block.Hash
+				violation.TxHash = transaction.Hash
+				if !check.report(violation) {
+					return false
+				}
+			}
+			if l.seen[transaction.Hash] && !duplicate {
+				if !check.transactionError(ErrDuplicateTransaction, block, transaction, "", "", "transaction was already mined") {
+					return false
+				}
+			}
+			l.seen[transaction.Hash] = true
+		}
+
+		available := l.balances[transaction.From] - l.stakes[transaction.From]
+		spent := transaction.Value + transaction.Fee
+		if signed {
+			l.balances[transaction.From] -= transaction.Value + transaction.Fee
+		}
+		l.balances[transaction.To] += transaction.Value
+
+		switch transaction.Type {
+		case StakeTransaction:
+			l.stakes[transaction.From] += transaction.Value
+		case UnstakeTransaction:
+			// the unstaked value comes back to the sender, only the fee is spent
+			spent = transaction.Fee
+			if staked := l.stakes[transaction.From]; transaction.Value > staked && !legacy {
+				if !check.transactionError(ErrInsufficientFunds, block, transaction, "<= "+strconv.FormatInt(staked, 10), strconv.FormatInt(transaction.Value, 10), "unstakes more than is staked") {
+					return false
+				}
+			}
+			l.stakes[transaction.From] -= min(transaction.Value, l.stakes[transaction.From])
+		}
+
+		if signed && !legacy && l.balances[transaction.From]-l.stakes[transaction.From] < 0 {
+			if !check.transactionError(ErrInsufficientFunds, block, transaction, "<= "+strconv.FormatInt(available, 10), strconv.FormatInt(spent, 10), "sender %s spends more than its balance", transaction.From) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
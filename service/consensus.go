@@ -0,0 +1,163 @@
+package service
+
+import (
+	"blockchain-backend/util"
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	ProofOfWork      = "pow"
+	ProofOfAuthority = "poa"
+	ProofOfStake     = "pos"
+)
+
+// ConsensusEngine decides who may produce the next block and how a block proves it was produced by them.
+// parents is the chain up to and including the parent of block, starting at the genesis block.
+type ConsensusEngine interface {
+	Name() string
+	// Prepare sets the difficulty fields of block before it is sealed on top of parents
+	Prepare(parents []Block, block *Block)
+	// Seal fills in the proof of the block (hash, nonce, signature), it stops with ctx.Err() when ctx is cancelled
+	Seal(ctx context.Context, parents []Block, block *Block, opts MiningOptions) error
+	// VerifyHeader checks the proof and difficulty of block, the transactions are checked by the caller
+	VerifyHeader(parents []Block, block *Block) error
+	// CalcDifficulty is the difficulty this node puts on the block mined on top of parents
+	CalcDifficulty(parents []Block) int64
+	// Work is what block adds to the weight of its chain, the heaviest chain wins a fork
+	Work(block *Block) *big.Int
+}
+
+func newConsensusEngine(bs *blockService, name string) (ConsensusEngine, error) {
+	switch name {
+	case "", ProofOfWork:
+		return &powEngine{blockService: bs}, nil
+	case ProofOfAuthority:
+		return newPoaEngine(bs)
+	case ProofOfStake:
+		return newPosEngine(bs)
+	default:
+		return nil, fmt.Errorf("consensus must be one of %s, %s, %s", ProofOfWork, ProofOfAuthority, ProofOfStake)
+	}
+}
+
+// loadSignerKey parses a hex private key with or without 0x and returns it without 0x, with its address.
+func loadSignerKey(privateKey string) (key, address string, err error) {
+	key = strings.TrimPrefix(privateKey, "0x")
+	keyPair, err := util.GetKeypairFromPrivateKey("0x" + key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, keyPair.Address, nil
+}
+
+// signBlock seals block with a signature over its hash instead of work, for the engines that pick
+// the block producer rather than let miners race.
+func signBlock(bs *blockService, block *Block, privateKey string) error {
+	block.Nonce = 0
+	block.Timestamp = time.Now().Unix()
+	block.Hash = bs.HashBlock(block, block.ParentHash)
+	block.Binary, _ = util.HexToBin(block.Hash)
+
+	signature, err := util.Sign(common.HexToHash(block.Hash).Bytes(), privateKey)
+	if err != nil {
+		return err
+	}
+	block.Signature = signature
+	return nil
+}
+
+// blockSigner recovers the address that signed block.
+func blockSigner(block *Block) (string, error) {
+	if block.Signature == "" {
+		return "", newRuleError(ErrInvalidSignature, "", "", "block %d is not signed", block.BlockNumber)
+	}
+	signer, err := util.RecoverAddress(common.HexToHash(block.Hash).Bytes(), block.Signature)
+	if err != nil {
+		return "", newRuleError(ErrInvalidSignature, "", block.Signature, "invalid block signature: %s", err)
+	}
+	return signer, nil
+}
+
+// powEngine is the proof-of-work of the blockService, the block hash read as a number must be at most the target
+// of the block. Difficulty is the target in leading zero bits, rounded down for BlockVersionTarget blocks.
+type powEngine struct {
+	blockService *blockService
+}
+
+func (pe *powEngine) Name() string {
+	return ProofOfWork
+}
+
+func (pe *powEngine) Seal(ctx context.Context, parents []Block, block *Block, opts MiningOptions) error {
+	return pe.blockService.mine(ctx, block, opts)
+}
+
+func (pe *powEngine) Prepare(parents []Block, block *Block) {
+	if block.Version < BlockVersionTarget {
+		block.Difficulty = pe.blockService.NextDifficulty(parents)
+		return
+	}
+	block.Bits = pe.blockService.NextBits(parents)
+	block.Difficulty = bitsDifficulty(block.Bits)
+}
+
+func (pe *powEngine) CalcDifficulty(parents []Block) int64 {
+	return bitsDifficulty(pe.blockService.NextBits(parents))
+}
+
+func (pe *powEngine) Work(block *Block) *big.Int {
+	return util.CalcWork(blockTarget(block))
+}
+
+// VerifyHeader checks the difficulty of block against the parents, with retargeting it must be the one computed
+// from them. Without retargeting the difficulty set on this node is a floor, a block may carry more work.
+func (pe *powEngine) VerifyHeader(parents []Block, block *Block) error {
+	interval, _ := pe.blockService.GetRetargetConfig()
+	if block.Version >= BlockVersionTarget {
+		expected := pe.blockService.NextBits(parents)
+		if interval > 0 && block.Bits != expected {
+			return newRuleError(ErrInvalidDifficulty, fmt.Sprintf("%08x", expected), fmt.Sprintf("%08x", block.Bits), "invalid bits")
+		}
+		if interval <= 0 && util.CompactToBig(block.Bits).Cmp(util.CompactToBig(expected)) > 0 {
+			return newRuleError(ErrInvalidDifficulty, fmt.Sprintf("<= %08x", expected), fmt.Sprintf("%08x", block.Bits), "bits are easier than the difficulty")
+		}
+		if expected := bitsDifficulty(block.Bits); block.Difficulty != expected {
+			return newRuleError(ErrInvalidDifficulty, strconv.FormatInt(expected, 10), strconv.FormatInt(block.Difficulty, 10), "difficulty does not match the bits")
+		}
+	} else {
+		expected := pe.blockService.NextDifficulty(parents)
+		if interval > 0 && block.Difficulty != expected {
+			return newRuleError(ErrInvalidDifficulty, strconv.FormatInt(expected, 10), strconv.FormatInt(block.Difficulty, 10), "invalid difficulty")
+		}
+		if interval <= 0 && block.Difficulty < expected {
+			return newRuleError(ErrInvalidDifficulty, ">= "+strconv.FormatInt(expected, 10), strconv.FormatInt(block.Difficulty, 10), "difficulty is below the difficulty of this node")
+		}
+	}
+
+	if block.Difficulty < 1 || block.Difficulty > maxDifficulty {
+		return newRuleError(ErrInvalidDifficulty, fmt.Sprintf("1 to %d", maxDifficulty), strconv.FormatInt(block.Difficulty, 10), "difficulty is out of range")
+	}
+
+	if hash := pe.blockService.HashBlock(block, parents[len(parents)-1].Hash); hash != block.Hash {
+		return newRuleError(ErrInvalidHash, hash, block.Hash, "invalid hash")
+	}
+
+	if target := blockTarget(block); !util.MeetsTarget(common.HexToHash(block.Hash), target) {
+		return newRuleError(ErrInsufficientWork, "<= "+hexutil.EncodeBig(target), block.Hash, "hash does not meet the target")
+	}
+
+	return nil
+}
+
+// bitsDifficulty is the difficulty of a compact target in whole leading zero bits.
+func bitsDifficulty(bits uint32) int64 {
+	return int64(util.TargetDifficulty(util.CompactToBig(bits)))
+}
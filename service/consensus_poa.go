@@ -0,0 +1,164 @@
+package service
+
+import (
+	"blockchain-backend/config"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// like Clique, a block signed by the in-turn signer weighs more so the in-turn chain wins a fork
+	diffInTurn int64 = 2
+	diffNoTurn int64 = 1
+)
+
+// poaEngine is a Clique-style proof-of-authority. A fixed set of signers takes turns, the signer of block n is
+// in turn when it is signers[n % len(signers)] in address order. Any other signer may step in for a missing
+// one, but no signer may sign again within len(signers)/2 blocks of its last block.
+type poaEngine struct {
+	blockService *blockService
+	signers      []string
+	signerKey    string // private key of this node, empty when this node only verifies
+	signer       string
+}
+
+func newPoaEngine(bs *blockService) (*poaEngine, error) {
+	var signers []string
+	for _, address := range strings.Split(config.ConfigEnv.PoaSigners, ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid proof-of-authority signer %s", address)
+		}
+		signers = append(signers, common.HexToAddress(address).Hex())
+	}
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("proof-of-authority needs at least one signer in POA_SIGNERS")
+	}
+	sort.Strings(signers)
+
+	pe := &poaEngine{
+		blockService: bs,
+		signers:      signers,
+	}
+
+	if config.ConfigEnv.PoaSignerKey != "" {
+		key, address, err := loadSignerKey(config.ConfigEnv.PoaSignerKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof-of-authority signer key: %w", err)
+		}
+		if !pe.isSigner(address) {
+			return nil, fmt.Errorf("signer key address %s is not in POA_SIGNERS", address)
+		}
+		pe.signerKey = key
+		pe.signer = address
+	}
+
+	return pe, nil
+}
+
+func (pe *poaEngine) Name() string {
+	return ProofOfAuthority
+}
+
+func (pe *poaEngine) isSigner(address string) bool {
+	for _, signer := range pe.signers {
+		if strings.Compare(signer, address) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (pe *poaEngine) inTurn(blockNumber int64, signer string) bool {
+	return strings.Compare(pe.signers[blockNumber%int64(len(pe.signers))], signer) == 0
+}
+
+func (pe *poaEngine) difficulty(blockNumber int64, signer string) int64 {
+	if pe.inTurn(blockNumber, signer) {
+		return diffInTurn
+	}
+	return diffNoTurn
+}
+
+// signedRecently reports whether signer signed one of the last len(signers)/2 blocks of parents.
+func (pe *poaEngine) signedRecently(parents []Block, signer string) bool {
+	for i := len(parents) - 1; i > 0 && i >= len(parents)-len(pe.signers)/2; i-- {
+		if recent, err := blockSigner(&parents[i]); err == nil && strings.Compare(recent, signer) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (pe *poaEngine) Prepare(parents []Block, block *Block) {
+	block.Difficulty = pe.CalcDifficulty(parents)
+}
+
+// Work of a signed block is its difficulty, there is no hashing to count.
+func (pe *poaEngine) Work(block *Block) *big.Int {
+	return big.NewInt(block.Difficulty)
+}
+
+func (pe *poaEngine) CalcDifficulty(parents []Block) int64 {
+	if len(parents) == 0 || pe.signer == "" {
+		return diffNoTurn
+	}
+	return pe.difficulty(parents[len(parents)-1].BlockNumber+1, pe.signer)
+}
+
+func (pe *poaEngine) Seal(ctx context.Context, parents []Block, block *Block, opts MiningOptions) error {
+	if pe.signerKey == "" {
+		return fmt.Errorf("this node has no signer key, set POA_SIGNER_KEY to produce blocks")
+	}
+	if pe.signedRecently(parents, pe.signer) {
+		return fmt.Errorf("signer %s signed recently, waiting for the other signers", pe.signer)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	opts.Progress.start(1)
+	defer opts.Progress.stop()
+
+	block.Difficulty = pe.difficulty(block.BlockNumber, pe.signer)
+	if err := signBlock(pe.blockService, block, pe.signerKey); err != nil {
+		return err
+	}
+	opts.Progress.update(common.HexToHash(block.Hash))
+
+	return nil
+}
+
+func (pe *poaEngine) VerifyHeader(parents []Block, block *Block) error {
+	parent := parents[len(parents)-1]
+	if hash := pe.blockService.HashBlock(block, parent.Hash); hash != block.Hash {
+		return newRuleError(ErrInvalidHash, hash, block.Hash, "invalid hash")
+	}
+	if block.Timestamp < parent.Timestamp {
+		return newRuleError(ErrInvalidTimestamp, ">= "+strconv.FormatInt(parent.Timestamp, 10), strconv.FormatInt(block.Timestamp, 10), "timestamp is before the parent block")
+	}
+
+	signer, err := blockSigner(block)
+	if err != nil {
+		return err
+	}
+	if !pe.isSigner(signer) {
+		return newRuleError(ErrInvalidSignature, strings.Join(pe.signers, ","), signer, "block signed by %s which is not an authorized signer", signer)
+	}
+	if pe.signedRecently(parents, signer) {
+		return newRuleError(ErrInvalidSignature, "", signer, "signer %s signed recently", signer)
+	}
+	if expected := pe.difficulty(block.BlockNumber, signer); block.Difficulty != expected {
+		return newRuleError(ErrInvalidDifficulty, strconv.FormatInt(expected, 10), strconv.FormatInt(block.Difficulty, 10), "invalid difficulty")
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"blockchain-backend/config"
+	"blockchain-backend/util"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// posDifficulty is the weight of every proof-of-stake block, there is no work to compare
+const posDifficulty int64 = 1
+
+// posEngine is a proof-of-stake where accounts lock balance with stake transactions. The proposer of the
+// next block is drawn from the validators weighted by stake, seeded with the hash of the parent block so
+// every node agrees on it. While nothing is staked any validator key may propose, so the chain can start.
+type posEngine struct {
+	blockService *blockService
+	validatorKey string // private key of this node, empty when this node only verifies
+	validator    string
+}
+
+func newPosEngine(bs *blockService) (*posEngine, error) {
+	pe := &posEngine{
+		blockService: bs,
+	}
+
+	if config.ConfigEnv.PosValidatorKey != "" {
+		key, address, err := loadSignerKey(config.ConfigEnv.PosValidatorKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof-of-stake validator key: %w", err)
+		}
+		pe.validatorKey = key
+		pe.validator = address
+	}
+
+	return pe, nil
+}
+
+func (pe *posEngine) Name() string {
+	return ProofOfStake
+}
+
+func (pe *posEngine) Prepare(parents []Block, block *Block) {
+	block.Difficulty = pe.CalcDifficulty(parents)
+}
+
+// Work of a signed block is its difficulty, there is no hashing to count.
+func (pe *posEngine) Work(block *Block) *big.Int {
+	return big.NewInt(block.Difficulty)
+}
+
+func (pe *posEngine) CalcDifficulty(parents []Block) int64 {
+	return posDifficulty
+}
+
+func (pe *posEngine) Seal(ctx context.Context, parents []Block, block *Block, opts MiningOptions) error {
+	if pe.validatorKey == "" {
+		return fmt.Errorf("this node has no validator key, set POS_VALIDATOR_KEY to propose blocks")
+	}
+	if proposer := Proposer(parents); proposer != "" && common.HexToAddress(proposer) != common.HexToAddress(pe.validator) {
+		return fmt.Errorf("validator %s is not the proposer of this block, expected %s", pe.validator, proposer)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	opts.Progress.start(1)
+	defer opts.Progress.stop()
+
+	block.Difficulty = posDifficulty
+	if err := signBlock(pe.blockService, block, pe.validatorKey); err != nil {
+		return err
+	}
+	opts.Progress.update(common.HexToHash(block.Hash))
+
+	return nil
+}
+
+func (pe *posEngine) VerifyHeader(parents []Block, block *Block) error {
+	parent := parents[len(parents)-1]
+	if hash := pe.blockService.HashBlock(block, parent.Hash); hash != block.Hash {
+		return newRuleError(ErrInvalidHash, hash, block.Hash, "invalid hash")
+	}
+	if block.Timestamp < parent.Timestamp {
+		return newRuleError(ErrInvalidTimestamp, ">= "+strconv.FormatInt(parent.Timestamp, 10), strconv.FormatInt(block.Timestamp, 10), "timestamp is before the parent block")
+	}
+	if block.Difficulty != posDifficulty {
+		return newRuleError(ErrInvalidDifficulty, strconv.FormatInt(posDifficulty, 10), strconv.FormatInt(block.Difficulty, 10), "invalid difficulty")
+	}
+
+	signer, err := blockSigner(block)
+	if err != nil {
+		return err
+	}
+	if proposer := Proposer(parents); proposer != "" && common.HexToAddress(proposer) != common.HexToAddress(signer) {
+		return newRuleError(ErrInvalidSignature, proposer, signer, "block signed by %s but the proposer is %s", signer, proposer)
+	}
+
+	return nil
+}
+
+// ValidatorSet is the proof-of-stake state after the last block.
+type ValidatorSet struct {
+	Stakes       map[string]int64 `json:"stakes"`
+	TotalStake   int64            `json:"total_stake"`
+	NextProposer string           `json:"next_proposer"` // empty while nothing is staked, any validator may propose
+}
+
+// chainStakes is the stake locked by every account after blocks, keyed by checksummed address since a sender
+// may be written in any case. An unstake never unlocks more than is locked.
+func chainStakes(blocks []Block) map[string]int64 {
+	stakes := make(map[string]int64)
+	for _, block := range blocks {
+		for _, transaction := range block.Transactions {
+			from := common.HexToAddress(transaction.From).Hex()
+			switch transaction.Type {
+			case StakeTransaction:
+				stakes[from] += transaction.Value
+			case UnstakeTransaction:
+				stakes[from] -= min(transaction.Value, stakes[from])
+			}
+		}
+	}
+
+	for address, stake := range stakes {
+		if stake == 0 {
+			delete(stakes, address)
+		}
+	}
+	return stakes
+}
+
+// Proposer is the validator that must propose the block after parents, or "" when nothing is staked.
+// A point in [0, total stake) is taken from keccak(parent hash) and the validators, in address order,
+// each own a range of the size of their stake.
+func Proposer(parents []Block) string {
+	if len(parents) == 0 {
+		return ""
+	}
+
+	stakes := chainStakes(parents)
+	validators := make([]string, 0, len(stakes))
+	var total int64
+	for address, stake := range stakes {
+		validators = append(validators, address)
+		total += stake
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Strings(validators)
+
+	seed := util.CryptoHash(common.HexToHash(parents[len(parents)-1].Hash).Bytes())
+	point := new(big.Int).Mod(new(big.Int).SetBytes(seed.Bytes()), big.NewInt(total)).Int64()
+	for _, validator := range validators {
+		if point < stakes[validator] {
+			return validator
+		}
+		point -= stakes[validator]
+	}
+	return validators[len(validators)-1]
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"blockchain-backend/util"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestPosLowercaseStaker checks that a validator staking with a lower case sender is still the proposer it signs
+// as, so it can seal blocks and the other nodes accept them.
+func TestPosLowercaseStaker(t *testing.T) {
+	wallet, err := util.GenerateKeyPair("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, validator, err := loadSignerKey(wallet.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs := &blockService{}
+	pe := &posEngine{blockService: bs, validatorKey: key, validator: validator}
+	genesis := bs.Genesis(0, 1)
+	stake := Block{
+		Version:     CurrentBlockVersion,
+		BlockNumber: 2,
+		Hash:        common.HexToHash("0x02").Hex(),
+		ParentHash:  genesis.Hash,
+		Transactions: []Transaction{{
+			Type:  StakeTransaction,
+			From:  strings.ToLower(validator),
+			To:    strings.ToLower(validator),
+			Value: 10,
+		}},
+	}
+	parents := []Block{*genesis, stake}
+
+	if stakes := chainStakes(parents); stakes[validator] != 10 || len(stakes) != 1 {
+		t.Fatalf("stakes = %v, want 10 staked by %s", stakes, validator)
+	}
+	if proposer := Proposer(parents); proposer != validator {
+		t.Fatalf("proposer = %s, want %s", proposer, validator)
+	}
+
+	block := &Block{
+		Version:     CurrentBlockVersion,
+		BlockNumber: 3,
+		ParentHash:  stake.Hash,
+		Miner:       validator,
+	}
+	pe.Prepare(parents, block)
+	if err := pe.Seal(context.Background(), parents, block, MiningOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pe.VerifyHeader(parents, block); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ChainComparison is the outcome of weighing a candidate chain against the local chain.
+type ChainComparison struct {
+	LocalLength          int    `json:"local_length"`
+	CandidateLength      int    `json:"candidate_length"`
+	LocalWork            string `json:"local_work"`
+	CandidateWork        string `json:"candidate_work"`
+	CommonAncestorHeight int64  `json:"common_ancestor_height"` // 0 when the chains share no block
+	Replaced             bool   `json:"replaced"`
+	OrphanedBlocks       int    `json:"orphaned_blocks"`
+	RestoredTransactions int    `json:"restored_transactions"`
+}
+
+// totalWork sums the work of every block after the genesis block, recomputed from the blocks
+// themselves so a chain cannot claim more work than it did.
+func (bls *blockchainService) totalWork(blocks []Block) *big.Int {
+	total := new(big.Int)
+	for i := 1; i < len(blocks); i++ {
+		total.Add(total, bls.blockService.Engine().Work(&blocks[i]))
+	}
+	return total
+}
+
+// commonAncestor is the index of the last block both chains share, -1 when they share none.
+func commonAncestor(local, candidate []Block) int {
+	ancestor := -1
+	for i := 0; i < len(local) && i < len(candidate); i++ {
+		if local[i].Hash != candidate[i].Hash || local[i].BlockNumber != candidate[i].BlockNumber {
+			break
+		}
+		ancestor = i
+	}
+	return ancestor
+}
+
+func (bls *blockchainService) compareChains(local, candidate []Block) (ChainComparison, int) {
+	ancestor := commonAncestor(local, candidate)
+	comparison := ChainComparison{
+		LocalLength:     len(local),
+		CandidateLength: len(candidate),
+		LocalWork:       hexutil.EncodeBig(bls.totalWork(local)),
+		CandidateWork:   hexutil.EncodeBig(bls.totalWork(candidate)),
+	}
+	if ancestor >= 0 {
+		comparison.CommonAncestorHeight = local[ancestor].BlockNumber
+	}
+	return comparison, ancestor
+}
+
+// reorgTransactions returns the pool transactions of the local blocks after ancestor that the candidate
+// chain does not include, and the hashes of the transactions the candidate chain mined after ancestor.
+// Coinbases and faucet grants belong to their block and are not restored.
+func reorgTransactions(local, candidate []Block, ancestor int) (orphaned []Transaction, mined []string) {
+	included := make(map[string]bool)
+	for _, block := range candidate[ancestor+1:] {
+		for _, transaction := range block.Transactions {
+			included[transaction.Hash] = true
+			mined = append(mined, transaction.Hash)
+		}
+	}
+
+	zeroAddress := common.Address{}.Hex()
+	for _, block := range local[ancestor+1:] {
+		for _, transaction := range block.Transactions {
+			if strings.Compare(transaction.From, zeroAddress) == 0 || included[transaction.Hash] {
+				continue
+			}
+			orphaned = append(orphaned, transaction)
+		}
+	}
+
+	return orphaned, mined
+}
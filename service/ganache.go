@@ -2,7 +2,7 @@ package service
 
 import (
 	"blockchain-backend/config"
-	"blockchain-backend/infras/redis"
+	"blockchain-backend/infras/storage"
 	"context"
 	"log"
 	"math/big"
@@ -20,18 +20,18 @@ type IGanacheService interface {
 
 type GanacheService struct {
 	rpc string
-	r   redis.IRedis
+	r   storage.IStorage
 }
 
-func NewGanacheService() IGanacheService {
+func NewGanacheService(store storage.IStorage) IGanacheService {
 	return &GanacheService{
 		rpc: config.ConfigEnv.Rpc,
-		r:   redis.RedisService,
+		r:   store,
 	}
 }
 
 func (gs *GanacheService) currentBlock() uint64 {
-	currentBlock := gs.r.Get(redis.CurrentBlockCrawledKey)
+	currentBlock := gs.r.Get(storage.CurrentBlockCrawledKey)
 	if currentBlock != "" {
 		block, err := strconv.Atoi(currentBlock)
 		if err != nil {
@@ -83,13 +83,13 @@ func (gs *GanacheService) CrawlBlock() error {
 				}
 				log.Println("Block", i, "Tx", tx.Hash().Hex(), "From", from.Hex(), "To", tx.To().Hex(), "Value", tx.Value().String())
 				// key = HistoryTransactionsKey + From
-				key := redis.HistoryTransactionsKey + from.Hex()
+				key := storage.HistoryTransactionsKey + from.Hex()
 
 				gs.r.SetSet(key, tx.Hash().Hex())
 			}
 
 			// save block to redis
-			gs.r.Set(redis.CurrentBlockCrawledKey, strconv.Itoa(int(i)))
+			gs.r.Set(storage.CurrentBlockCrawledKey, strconv.Itoa(int(i)))
 
 		}
 	})
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"blockchain-backend/infras/storage"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// ChainVersion is the format of the chain stored in redis, bump it together with a new migration.
+const ChainVersion int64 = 3
+
+type chainMigration struct {
+	version     int64
+	description string
+	migrate     func(bls *blockchainService, chain Chain) (Chain, error)
+}
+
+var chainMigrations = []chainMigration{
+	{
+		version:     1,
+		description: "canonical binary block encoding",
+		migrate:     migrateCanonicalEncoding,
+	},
+	{
+		version:     2,
+		description: "cumulative chain work per block",
+		migrate:     migrateChainWork,
+	},
+	{
+		version:     3,
+		description: "per-block storage",
+		migrate:     migrateBlockStore,
+	},
+}
+
+// Migrate brings the chain loaded from redis up to ChainVersion and records the version it reached. A migrated
+// chain is written back to the block store.
+func (bls *blockchainService) Migrate() error {
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+
+	var version int64
+	if stored := bls.store.Get(storage.ChainVersionKey); stored != "" {
+		var err error
+		version, err = strconv.ParseInt(stored, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid chain version %q: %w", stored, err)
+		}
+	}
+
+	chain := bls.chain
+	migrated := false
+	for _, migration := range chainMigrations {
+		if migration.version <= version {
+			continue
+		}
+
+		log.Println("Migrating chain to version", migration.version, "-", migration.description)
+		next, err := migration.migrate(bls, chain)
+		if err != nil {
+			return fmt.Errorf("migrate chain to version %d: %w", migration.version, err)
+		}
+		chain = next
+		version = migration.version
+		migrated = true
+	}
+
+	bls.chain = chain
+	if migrated {
+		bls.rebuildTree()
+		bls.rewriteStore()
+		bls.store.Del(storage.ChainKey)
+	}
+	bls.store.Set(storage.ChainVersionKey, strconv.FormatInt(version, 10))
+	bls.loadTree()
+	return nil
+}
+
+// migrateCanonicalEncoding keeps a chain stored before block versions existed as BlockVersionLegacy
+// blocks when ALLOW_LEGACY_BLOCKS is set, otherwise the node refuses to start so the chain is not lost.
+// Blocks without a version field already decode as BlockVersionLegacy, so only validation is needed.
+func migrateCanonicalEncoding(bls *blockchainService, chain Chain) (Chain, error) {
+	if len(chain.Blocks) == 0 {
+		return chain, nil
+	}
+
+	if !bls.blockService.IsSupportedVersion(BlockVersionLegacy) {
+		return Chain{}, fmt.Errorf("found a legacy chain of %d blocks, set ALLOW_LEGACY_BLOCKS to keep it or clear the storage to start over", len(chain.Blocks))
+	}
+
+	if err := bls.validateChain(chain, nil); err != nil {
+		log.Println("Legacy chain is invalid at", err, ", keeping it so it can be inspected")
+	} else {
+		log.Println("Legacy chain of", len(chain.Blocks), "blocks is valid")
+	}
+
+	return chain, nil
+}
+
+// migrateChainWork fills in the ChainWork of blocks stored before it existed. The blocks keep their
+// version, so their proof-of-work is still checked against Difficulty instead of Bits.
+func migrateChainWork(bls *blockchainService, chain Chain) (Chain, error) {
+	blocks := append([]Block{}, chain.Blocks...)
+	bls.fillChainWork(blocks)
+	return Chain{Blocks: blocks}, nil
+}
+
+// migrateBlockStore moves a chain stored as one JSON blob under ChainKey to the block store, Migrate writes
+// every migrated chain block by block and removes the blob.
+func migrateBlockStore(bls *blockchainService, chain Chain) (Chain, error) {
+	return chain, nil
+}
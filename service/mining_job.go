@@ -0,0 +1,283 @@
+package service
+
+import (
+	"blockchain-backend/util"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type MiningJobStatus string
+
+// maxFinishedJobs is how many finished jobs are kept to be looked up, older ones are dropped.
+const maxFinishedJobs = 100
+
+const (
+	MiningJobRunning   MiningJobStatus = "RUNNING"
+	MiningJobSucceeded MiningJobStatus = "SUCCEEDED"
+	MiningJobFailed    MiningJobStatus = "FAILED"
+	MiningJobCancelled MiningJobStatus = "CANCELLED"
+)
+
+// MiningProgress is updated by the proof-of-work workers so a running search can be observed.
+// A nil *MiningProgress is valid and ignores all updates.
+type MiningProgress struct {
+	attempts  atomic.Int64
+	bestZeros atomic.Int64
+	hasBest   atomic.Bool
+	workers   atomic.Int64
+	startedAt atomic.Int64 // unix nano
+	stoppedAt atomic.Int64 // unix nano
+
+	mu       sync.RWMutex
+	bestHash string
+}
+
+func (mp *MiningProgress) start(workers int) {
+	if mp == nil {
+		return
+	}
+	mp.workers.Store(int64(workers))
+	mp.startedAt.Store(time.Now().UnixNano())
+}
+
+func (mp *MiningProgress) stop() {
+	if mp == nil {
+		return
+	}
+	mp.stoppedAt.Store(time.Now().UnixNano())
+}
+
+func (mp *MiningProgress) update(hash common.Hash) {
+	if mp == nil {
+		return
+	}
+	mp.attempts.Add(1)
+
+	// only take the lock when this attempt beats the best one seen so far
+	zeros := int64(util.LeadingZeroBits(hash))
+	if mp.hasBest.Load() && zeros <= mp.bestZeros.Load() {
+		return
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if !mp.hasBest.Load() || zeros > mp.bestZeros.Load() {
+		mp.bestHash = hash.Hex()
+		mp.bestZeros.Store(zeros)
+		mp.hasBest.Store(true)
+	}
+}
+
+func (mp *MiningProgress) snapshot() (attempts int64, bestHash string, bestZeros int, workers int, hashRate float64) {
+	mp.mu.RLock()
+	bestHash = mp.bestHash
+	mp.mu.RUnlock()
+
+	attempts = mp.attempts.Load()
+	if startedAt := mp.startedAt.Load(); startedAt > 0 {
+		end := time.Now().UnixNano()
+		if stoppedAt := mp.stoppedAt.Load(); stoppedAt > 0 {
+			end = stoppedAt
+		}
+		if elapsed := time.Duration(end - startedAt); elapsed > 0 {
+			hashRate = float64(attempts) / elapsed.Seconds()
+		}
+	}
+	return attempts, bestHash, int(mp.bestZeros.Load()), int(mp.workers.Load()), hashRate
+}
+
+// MiningJob is a point-in-time view of a mining job.
+type MiningJob struct {
+	ID          string          `json:"id"`
+	Status      MiningJobStatus `json:"status"`
+	Miner       string          `json:"miner"`
+	Data        string          `json:"data"`
+	Position    int64           `json:"position"`
+	NonceTried  int64           `json:"nonce_tried"`
+	BestHash    string          `json:"best_hash"`
+	BestZeros   int             `json:"best_zeros"`
+	Workers     int             `json:"workers"`
+	HashRate    float64         `json:"hash_rate"`
+	StartedAt   int64           `json:"started_at"`
+	ElapsedMs   int64           `json:"elapsed_ms"`
+	Block       *Block          `json:"block,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	startedTime time.Time
+}
+
+type miningJob struct {
+	view       MiningJob
+	finishedAt time.Time
+	progress   *MiningProgress
+	cancel     context.CancelFunc
+}
+
+type IMiningJobService interface {
+	Start(data, miner string, position int64, workers int) (MiningJob, error)
+	Get(id string) (MiningJob, error)
+	List() []MiningJob
+	Cancel(id string) (MiningJob, error)
+}
+
+type miningJobService struct {
+	mu            sync.Mutex
+	lastID        int64
+	jobs          map[string]*miningJob
+	blockChainSvc IBlockchainService
+}
+
+func NewMiningJobService(blockChainSvc IBlockchainService) IMiningJobService {
+	return &miningJobService{
+		jobs:          make(map[string]*miningJob),
+		blockChainSvc: blockChainSvc,
+	}
+}
+
+// Start mines a block in the background, only one job can run at a time since every job draws from the same pool.
+func (mjs *miningJobService) Start(data, miner string, position int64, workers int) (MiningJob, error) {
+	mjs.mu.Lock()
+	defer mjs.mu.Unlock()
+
+	for _, job := range mjs.jobs {
+		if job.view.Status == MiningJobRunning {
+			return MiningJob{}, fmt.Errorf("mining job %s is already running", job.view.ID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mjs.lastID++
+	job := &miningJob{
+		view: MiningJob{
+			ID:          strconv.FormatInt(mjs.lastID, 10),
+			Status:      MiningJobRunning,
+			Miner:       miner,
+			Data:        data,
+			Position:    position,
+			Workers:     workers,
+			StartedAt:   time.Now().Unix(),
+			startedTime: time.Now(),
+		},
+		progress: &MiningProgress{},
+		cancel:   cancel,
+	}
+	mjs.jobs[job.view.ID] = job
+
+	go mjs.run(ctx, job)
+
+	return mjs.viewOf(job), nil
+}
+
+func (mjs *miningJobService) run(ctx context.Context, job *miningJob) {
+	block, err := mjs.blockChainSvc.NewBlock(ctx, job.view.Data, job.view.Miner, job.view.Position, MiningOptions{
+		Workers:  job.view.Workers,
+		Progress: job.progress,
+	})
+
+	mjs.mu.Lock()
+	defer mjs.mu.Unlock()
+	job.cancel()
+	job.finishedAt = time.Now()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.view.Status = MiningJobCancelled
+	case err != nil:
+		job.view.Status = MiningJobFailed
+		job.view.Error = err.Error()
+	default:
+		job.view.Status = MiningJobSucceeded
+		job.view.Block = block
+	}
+	mjs.evict()
+}
+
+// evict drops the jobs that finished first once more than maxFinishedJobs finished, it must be called with
+// mjs.mu held.
+func (mjs *miningJobService) evict() {
+	finished := make([]*miningJob, 0, len(mjs.jobs))
+	for _, job := range mjs.jobs {
+		if job.view.Status != MiningJobRunning {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].finishedAt.Before(finished[j].finishedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(mjs.jobs, job.view.ID)
+	}
+}
+
+func (mjs *miningJobService) Get(id string) (MiningJob, error) {
+	mjs.mu.Lock()
+	defer mjs.mu.Unlock()
+
+	job, ok := mjs.jobs[id]
+	if !ok {
+		return MiningJob{}, fmt.Errorf("mining job not found")
+	}
+	return mjs.viewOf(job), nil
+}
+
+func (mjs *miningJobService) List() []MiningJob {
+	mjs.mu.Lock()
+	defer mjs.mu.Unlock()
+
+	jobs := make([]MiningJob, 0, len(mjs.jobs))
+	for _, job := range mjs.jobs {
+		jobs = append(jobs, mjs.viewOf(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].startedTime.Before(jobs[j].startedTime)
+	})
+	return jobs
+}
+
+func (mjs *miningJobService) Cancel(id string) (MiningJob, error) {
+	mjs.mu.Lock()
+	job, ok := mjs.jobs[id]
+	if !ok {
+		mjs.mu.Unlock()
+		return MiningJob{}, fmt.Errorf("mining job not found")
+	}
+	if job.view.Status != MiningJobRunning {
+		mjs.mu.Unlock()
+		return MiningJob{}, fmt.Errorf("mining job is not running")
+	}
+	job.cancel()
+	mjs.mu.Unlock()
+
+	// wait for the worker to observe the cancellation so the caller sees the final state
+	for {
+		view, err := mjs.Get(id)
+		if err != nil || view.Status != MiningJobRunning {
+			return view, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// viewOf must be called with mjs.mu held.
+func (mjs *miningJobService) viewOf(job *miningJob) MiningJob {
+	view := job.view
+	view.NonceTried, view.BestHash, view.BestZeros, view.Workers, view.HashRate = job.progress.snapshot()
+
+	end := time.Now()
+	if !job.finishedAt.IsZero() {
+		end = job.finishedAt
+	}
+	view.ElapsedMs = end.Sub(view.startedTime).Milliseconds()
+	return view
+}
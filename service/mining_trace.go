@@ -0,0 +1,105 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type MiningTraceEventType string
+
+const (
+	MiningTraceStarted MiningTraceEventType = "started"
+	MiningTraceAttempt MiningTraceEventType = "attempt"
+	MiningTraceFound   MiningTraceEventType = "found"
+	MiningTraceStopped MiningTraceEventType = "stopped"
+)
+
+const (
+	defaultTraceSampleRate = 1000
+	// events a slow viewer may fall behind by before it starts missing events
+	traceSubscriberBuffer = 256
+)
+
+// MiningTraceEvent is one step of a proof-of-work search, attempts are sampled every SampleRate hashes.
+type MiningTraceEvent struct {
+	Type        MiningTraceEventType `json:"type"`
+	BlockNumber int64                `json:"block_number"`
+	Difficulty  int64                `json:"difficulty,omitempty"`
+	Target      string               `json:"target,omitempty"`
+	Workers     int                  `json:"workers,omitempty"`
+	SampleRate  int64                `json:"sample_rate,omitempty"`
+	Worker      int                  `json:"worker,omitempty"`
+	Nonce       int64                `json:"nonce,omitempty"`
+	Hash        string               `json:"hash,omitempty"`
+	Binary      string               `json:"binary,omitempty"`
+	Zeros       int                  `json:"zeros,omitempty"`
+	BestZeros   int                  `json:"best_zeros,omitempty"`
+	Attempts    int64                `json:"attempts"`
+	DurationMs  int64                `json:"duration_ms,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// miningTrace fans proof-of-work events out to every viewer. Publishing never blocks the miner, a viewer
+// whose buffer is full misses events, and nothing is built at all while no one is watching.
+type miningTrace struct {
+	mu          sync.RWMutex
+	subscribers map[int64]chan MiningTraceEvent
+	nextID      int64
+	watching    atomic.Int64
+	sampleRate  atomic.Int64
+}
+
+func newMiningTrace(sampleRate int64) *miningTrace {
+	if sampleRate <= 0 {
+		sampleRate = defaultTraceSampleRate
+	}
+
+	mt := &miningTrace{
+		subscribers: make(map[int64]chan MiningTraceEvent),
+	}
+	mt.sampleRate.Store(sampleRate)
+	return mt
+}
+
+func (mt *miningTrace) subscribe() (<-chan MiningTraceEvent, func()) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	id := mt.nextID
+	mt.nextID++
+	events := make(chan MiningTraceEvent, traceSubscriberBuffer)
+	mt.subscribers[id] = events
+	mt.watching.Add(1)
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			mt.mu.Lock()
+			defer mt.mu.Unlock()
+			delete(mt.subscribers, id)
+			mt.watching.Add(-1)
+			close(events)
+		})
+	}
+}
+
+func (mt *miningTrace) active() bool {
+	return mt.watching.Load() > 0
+}
+
+// sampled reports whether the attempt-th hash of a search is traced.
+func (mt *miningTrace) sampled(attempt int64) bool {
+	return mt.active() && attempt%mt.sampleRate.Load() == 0
+}
+
+func (mt *miningTrace) publish(event MiningTraceEvent) {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+
+	for _, events := range mt.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"blockchain-backend/infras/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	minResubscribeDelay = time.Second
+	maxResubscribeDelay = time.Minute
+)
+
+// SyncMessage is a block or a transaction a node publishes to the nodes sharing its storage.
+type SyncMessage struct {
+	NodeID      string       `json:"node_id"`
+	Block       *Block       `json:"block,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+}
+
+type INodeSyncService interface {
+	NodeID() string
+	Start() error
+	Stop()
+}
+
+// nodeSyncService keeps the nodes that share one redis, each with its own state under NODE:<id>:, in step.
+// Every block a node mines or accepts is published on ChannelSyncNodeKey and every new pool transaction on
+// ChannelSyncTransactionKey, the other nodes validate them before they apply them. A block whose parent is
+// missing is completed from the shared blocks by hash. Bad messages are logged and dropped, a lost
+// subscription is set up again.
+type nodeSyncService struct {
+	mu            sync.Mutex
+	nodeID        string
+	pubsub        storage.IPubSub
+	store         storage.IStorage
+	blockStore    IBlockStore
+	blockChainSvc IBlockchainService
+	cancel        context.CancelFunc
+	done          chan struct{}
+	// received holds the hashes being applied from messages so they are not published again
+	received map[string]bool
+}
+
+// NewNodeSyncService publishes from now on. store is the storage shared by the nodes and must implement
+// storage.IPubSub, blockStore must keep its blocks by hash in store.
+func NewNodeSyncService(blockChainSvc IBlockchainService, transactionPoolSvc ITransactionPoolService, blockStore IBlockStore, store storage.IStorage, nodeID string) (INodeSyncService, error) {
+	pubsub, ok := store.(storage.IPubSub)
+	if !ok {
+		return nil, fmt.Errorf("the storage backend cannot publish to other nodes")
+	}
+	if nodeID == "" {
+		return nil, fmt.Errorf("a node that syncs with other nodes needs a node id")
+	}
+
+	ns := &nodeSyncService{
+		nodeID:        nodeID,
+		pubsub:        pubsub,
+		store:         store,
+		blockStore:    blockStore,
+		blockChainSvc: blockChainSvc,
+		received:      make(map[string]bool),
+	}
+	blockChainSvc.OnNewBlock(ns.publishBlock)
+	transactionPoolSvc.OnNewTransaction(ns.publishTransaction)
+	return ns, nil
+}
+
+func (ns *nodeSyncService) NodeID() string {
+	return ns.nodeID
+}
+
+func (ns *nodeSyncService) publishBlock(block Block) {
+	if ns.isReceived(block.Hash) {
+		return
+	}
+	ns.publish(storage.ChannelSyncNodeKey, SyncMessage{NodeID: ns.nodeID, Block: &block})
+}
+
+func (ns *nodeSyncService) publishTransaction(transaction Transaction) {
+	if ns.isReceived(transaction.Hash) {
+		return
+	}
+	ns.publish(storage.ChannelSyncTransactionKey, SyncMessage{NodeID: ns.nodeID, Transaction: &transaction})
+}
+
+func (ns *nodeSyncService) publish(channel string, message SyncMessage) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Println("Node sync:", err)
+		return
+	}
+	ns.store.Publish(channel, string(payload))
+}
+
+func (ns *nodeSyncService) isReceived(hash string) bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.received[hash]
+}
+
+// applying marks hash as received while apply runs, so what apply adds is not published back.
+func (ns *nodeSyncService) applying(hash string, apply func() error) error {
+	ns.mu.Lock()
+	ns.received[hash] = true
+	ns.mu.Unlock()
+
+	defer func() {
+		ns.mu.Lock()
+		delete(ns.received, hash)
+		ns.mu.Unlock()
+	}()
+	return apply()
+}
+
+// Start subscribes to the other nodes in the background.
+func (ns *nodeSyncService) Start() error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if ns.cancel != nil {
+		return fmt.Errorf("node sync is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ns.cancel = cancel
+	ns.done = make(chan struct{})
+	go ns.run(ctx, ns.done)
+
+	log.Println("Node sync started as node", ns.nodeID)
+	return nil
+}
+
+func (ns *nodeSyncService) Stop() {
+	ns.mu.Lock()
+	cancel, done := ns.cancel, ns.done
+	ns.cancel = nil
+	ns.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// run receives until ctx is done and subscribes again, waiting longer after every failure, when the
+// subscription is lost.
+func (ns *nodeSyncService) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	delay := minResubscribeDelay
+	for {
+		received, err := ns.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			delay = minResubscribeDelay
+		}
+
+		log.Println("Node sync subscription lost:", err, "- subscribing again in", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
+
+// receive handles messages until the subscription fails and reports whether any message arrived.
+func (ns *nodeSyncService) receive(ctx context.Context) (bool, error) {
+	subscription := ns.pubsub.Subscribe(storage.ChannelSyncNodeKey, storage.ChannelSyncTransactionKey)
+	defer subscription.Close()
+
+	received := false
+	for {
+		channel, payload, err := subscription.ReceiveMessage(ctx)
+		if err != nil {
+			return received, err
+		}
+		received = true
+
+		if err := ns.handle(channel, payload); err != nil {
+			log.Println("Node sync dropped a message on", channel, ":", err)
+		}
+	}
+}
+
+func (ns *nodeSyncService) handle(channel string, payload string) error {
+	var message SyncMessage
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		return err
+	}
+	if message.NodeID == "" {
+		return fmt.Errorf("message has no node id")
+	}
+	if message.NodeID == ns.nodeID {
+		return nil
+	}
+
+	switch channel {
+	case storage.ChannelSyncNodeKey:
+		if message.Block == nil {
+			return fmt.Errorf("message from node %s has no block", message.NodeID)
+		}
+		block := *message.Block
+		return ns.applying(block.Hash, func() error {
+			err := ns.blockChainSvc.AcceptBranch(block, ns.blockStore.BlockByHash)
+			if errors.Is(err, ErrUnknownParent) {
+				return fmt.Errorf("block %d from node %s: %w", block.BlockNumber, message.NodeID, err)
+			}
+			return err
+		})
+	case storage.ChannelSyncTransactionKey:
+		if message.Transaction == nil {
+			return fmt.Errorf("message from node %s has no transaction", message.NodeID)
+		}
+		transaction := *message.Transaction
+		return ns.applying(transaction.Hash, func() error {
+			return ns.blockChainSvc.AcceptTransaction(transaction)
+		})
+	default:
+		return fmt.Errorf("unknown channel")
+	}
+}
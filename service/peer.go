@@ -0,0 +1,475 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/robfig/cron/v3"
+)
+
+const peerTimeout = 10 * time.Second
+
+// Peer is another node, Height, Head and TotalWork are what it reported last.
+type Peer struct {
+	URL       string `json:"url"`
+	Height    int64  `json:"height"`
+	Head      string `json:"head"`
+	TotalWork string `json:"total_work"`
+	LastSeen  int64  `json:"last_seen,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NodeStatus is what a node tells its peers about its canonical chain.
+type NodeStatus struct {
+	Height    int64  `json:"height"`
+	Head      string `json:"head"`
+	TotalWork string `json:"total_work"`
+}
+
+// SyncResult is the outcome of a sync, Synced is set when the chain of Peer replaced the local chain.
+type SyncResult struct {
+	Peer       string           `json:"peer,omitempty"`
+	Synced     bool             `json:"synced"`
+	Comparison *ChainComparison `json:"comparison,omitempty"`
+}
+
+type IPeerService interface {
+	AddPeer(peerURL string) (Peer, error)
+	RemovePeer(peerURL string) error
+	GetPeers() []Peer
+	Status() NodeStatus
+	ReceiveBlock(block Block, from string) error
+	ReceiveTransaction(transaction Transaction) error
+	Sync() (SyncResult, error)
+	SyncWith(peerURL string) (SyncResult, error)
+	Start(interval int64) error
+	Stop()
+}
+
+// peerService talks to other nodes over their HTTP API. Every block this node mines or accepts and every new
+// pool transaction is announced to all peers, which relay what is new to them, so announcements spread
+// through the network and stop at nodes that already have them.
+type peerService struct {
+	mu                 sync.Mutex
+	peers              map[string]*Peer
+	selfURL            string
+	client             *http.Client
+	cron               *cron.Cron
+	syncMu             sync.Mutex
+	blockChainSvc      IBlockchainService
+	transactionPoolSvc ITransactionPoolService
+}
+
+// NewPeerService announces to the peers from now on, selfURL is where peers fetch the blocks this node announces.
+func NewPeerService(blockChainSvc IBlockchainService, transactionPoolSvc ITransactionPoolService, selfURL string) IPeerService {
+	ps := &peerService{
+		peers:              make(map[string]*Peer),
+		selfURL:            strings.TrimSuffix(selfURL, "/"),
+		client:             &http.Client{Timeout: peerTimeout},
+		blockChainSvc:      blockChainSvc,
+		transactionPoolSvc: transactionPoolSvc,
+	}
+	blockChainSvc.OnNewBlock(ps.announceBlock)
+	transactionPoolSvc.OnNewTransaction(ps.announceTransaction)
+	return ps
+}
+
+func normalizePeerURL(peerURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(peerURL))
+	if err != nil {
+		return "", err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("peer url must be an http or https base url such as http://localhost:8080")
+	}
+	return strings.TrimSuffix(parsed.String(), "/"), nil
+}
+
+// AddPeer registers a peer and asks it for its status, a peer that cannot be reached yet is kept with LastError set.
+func (ps *peerService) AddPeer(peerURL string) (Peer, error) {
+	peerURL, err := normalizePeerURL(peerURL)
+	if err != nil {
+		return Peer{}, err
+	}
+	if peerURL == ps.selfURL {
+		return Peer{}, fmt.Errorf("a node cannot be its own peer")
+	}
+
+	ps.mu.Lock()
+	if _, ok := ps.peers[peerURL]; !ok {
+		ps.peers[peerURL] = &Peer{URL: peerURL}
+		log.Println("Added peer", peerURL)
+	}
+	ps.mu.Unlock()
+
+	_, _ = ps.refresh(peerURL)
+	return ps.peer(peerURL), nil
+}
+
+func (ps *peerService) RemovePeer(peerURL string) error {
+	peerURL, err := normalizePeerURL(peerURL)
+	if err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.peers[peerURL]; !ok {
+		return fmt.Errorf("peer not found")
+	}
+	delete(ps.peers, peerURL)
+	return nil
+}
+
+func (ps *peerService) GetPeers() []Peer {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peers := make([]Peer, 0, len(ps.peers))
+	for _, peer := range ps.peers {
+		peers = append(peers, *peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].URL < peers[j].URL
+	})
+	return peers
+}
+
+func (ps *peerService) peer(peerURL string) Peer {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if peer, ok := ps.peers[peerURL]; ok {
+		return *peer
+	}
+	return Peer{URL: peerURL}
+}
+
+func (ps *peerService) Status() NodeStatus {
+	blocks := ps.blockChainSvc.GetBlocks().Blocks
+	if len(blocks) == 0 {
+		return NodeStatus{TotalWork: hexutil.EncodeBig(new(big.Int))}
+	}
+
+	head := blocks[len(blocks)-1]
+	return NodeStatus{
+		Height:    head.BlockNumber,
+		Head:      head.Hash,
+		TotalWork: head.ChainWork,
+	}
+}
+
+// refresh asks a peer for its status and records the answer on the registered peer.
+func (ps *peerService) refresh(peerURL string) (NodeStatus, error) {
+	var status NodeStatus
+	err := ps.get(peerURL+"/peer/status", &status)
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if peer, ok := ps.peers[peerURL]; ok {
+		if err != nil {
+			peer.LastError = err.Error()
+		} else {
+			peer.Height, peer.Head, peer.TotalWork = status.Height, status.Head, status.TotalWork
+			peer.LastSeen = time.Now().Unix()
+			peer.LastError = ""
+		}
+	}
+	return status, err
+}
+
+func (ps *peerService) record(peerURL string, err error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if peer, ok := ps.peers[peerURL]; ok {
+		if err != nil {
+			peer.LastError = err.Error()
+			return
+		}
+		peer.LastSeen = time.Now().Unix()
+		peer.LastError = ""
+	}
+}
+
+func (ps *peerService) peerURLs() []string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	urls := make([]string, 0, len(ps.peers))
+	for peerURL := range ps.peers {
+		urls = append(urls, peerURL)
+	}
+	return urls
+}
+
+// announceBlock runs on the path that mined or accepted the block, so the peers are contacted in the background.
+func (ps *peerService) announceBlock(block Block) {
+	for _, peerURL := range ps.peerURLs() {
+		go func(peerURL string) {
+			ps.record(peerURL, ps.post(peerURL+"/peer/blocks", map[string]any{"block": block, "from": ps.selfURL}))
+		}(peerURL)
+	}
+}
+
+func (ps *peerService) announceTransaction(transaction Transaction) {
+	for _, peerURL := range ps.peerURLs() {
+		go func(peerURL string) {
+			ps.record(peerURL, ps.post(peerURL+"/peer/transactions", map[string]any{"transaction": transaction, "from": ps.selfURL}))
+		}(peerURL)
+	}
+}
+
+// ReceiveBlock accepts a block announced by the node at from. Missing ancestors are fetched from it by hash,
+// a node that is further behind than AcceptBranch fetches syncs with it. A from that is not a registered peer
+// is ignored, so a caller cannot make this node fetch from or sync with any URL.
+func (ps *peerService) ReceiveBlock(block Block, from string) error {
+	if from != "" {
+		var err error
+		if from, err = normalizePeerURL(from); err != nil {
+			return err
+		}
+		if !ps.isPeer(from) {
+			from = ""
+		}
+	}
+
+	if ps.blockChainSvc.BlockLength() == 0 && from != "" {
+		_, err := ps.SyncWith(from)
+		return err
+	}
+
+	if from == "" {
+		return ps.blockChainSvc.AcceptBlock(block)
+	}
+
+	err := ps.blockChainSvc.AcceptBranch(block, func(hash string) (Block, error) {
+		var parent Block
+		err := ps.get(from+"/peer/blocks/"+hash, &parent)
+		return parent, err
+	})
+	if !errors.Is(err, ErrUnknownParent) {
+		return err
+	}
+
+	_, err = ps.SyncWith(from)
+	return err
+}
+
+func (ps *peerService) isPeer(peerURL string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	_, ok := ps.peers[peerURL]
+	return ok
+}
+
+// ReceiveTransaction adds a transaction announced by another node to the pool.
+func (ps *peerService) ReceiveTransaction(transaction Transaction) error {
+	return ps.blockChainSvc.AcceptTransaction(transaction)
+}
+
+// Sync asks every peer for its status and syncs with the one with the most work when it has more work than
+// this node.
+func (ps *peerService) Sync() (SyncResult, error) {
+	local := ps.Status()
+
+	var best string
+	bestWork := decodeWork(local.TotalWork)
+	for _, peerURL := range ps.peerURLs() {
+		status, err := ps.refresh(peerURL)
+		if err != nil {
+			continue
+		}
+		if work := decodeWork(status.TotalWork); work.Cmp(bestWork) > 0 {
+			best, bestWork = peerURL, work
+		}
+	}
+
+	if best == "" {
+		return SyncResult{}, nil
+	}
+	return ps.SyncWith(best)
+}
+
+// SyncWith replaces the local chain with the chain of the peer under the rules of ReplaceChain. Only the blocks
+// after the highest block both chains share are downloaded, and only from a registered peer.
+func (ps *peerService) SyncWith(peerURL string) (SyncResult, error) {
+	peerURL, err := normalizePeerURL(peerURL)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if !ps.isPeer(peerURL) {
+		return SyncResult{}, fmt.Errorf("%s is not a registered peer", peerURL)
+	}
+
+	ps.syncMu.Lock()
+	defer ps.syncMu.Unlock()
+
+	result := SyncResult{Peer: peerURL}
+	status, err := ps.refresh(peerURL)
+	if err != nil {
+		return result, err
+	}
+	if decodeWork(status.TotalWork).Cmp(decodeWork(ps.Status().TotalWork)) <= 0 {
+		return result, nil
+	}
+
+	// step back from the lower head, twice as far each time, until the peer has the same block
+	local := ps.blockChainSvc.GetBlocks().Blocks
+	height := min(int64(len(local)), status.Height)
+	for step := int64(1); height > 0; step *= 2 {
+		var block Block
+		if err := ps.get(fmt.Sprintf("%s/block/%d", peerURL, height), &block); err != nil {
+			return result, fmt.Errorf("fetch block %d from %s: %w", height, peerURL, err)
+		}
+		if block.Hash == local[height-1].Hash {
+			break
+		}
+		height = max(height-step, 0)
+	}
+
+	blocks, err := ps.fetchBlocks(peerURL, height+1)
+	if err != nil {
+		return result, err
+	}
+
+	comparison, err := ps.blockChainSvc.ReplaceChain(Chain{Blocks: append(local[:height:height], blocks...)})
+	result.Comparison = &comparison
+	if err != nil {
+		return result, err
+	}
+
+	log.Println("Synced", len(blocks), "blocks from", peerURL)
+	result.Synced = true
+	return result, nil
+}
+
+// fetchBlocks downloads the canonical blocks of a peer from block number from on as NDJSON.
+func (ps *peerService) fetchBlocks(peerURL string, from int64) ([]Block, error) {
+	resp, err := ps.client.Get(fmt.Sprintf("%s/block/export?format=%s&from=%d", peerURL, NDJSON, from))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeResponse(resp, nil)
+	}
+
+	next, err := newBlockReader(resp.Body, NDJSON)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []Block
+	for {
+		block, err := next()
+		if errors.Is(err, io.EOF) {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetch blocks from %s: %w", peerURL, err)
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// Start syncs with the best peer every interval seconds.
+func (ps *peerService) Start(interval int64) error {
+	if interval <= 0 {
+		return fmt.Errorf("sync interval must be positive")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.cron != nil {
+		return fmt.Errorf("peer sync is already running")
+	}
+
+	c := cron.New()
+	if _, err := c.AddFunc(fmt.Sprintf("@every %ds", interval), func() {
+		if _, err := ps.Sync(); err != nil {
+			log.Println("Peer sync:", err)
+		}
+	}); err != nil {
+		return err
+	}
+	ps.cron = c
+	c.Start()
+	return nil
+}
+
+func (ps *peerService) Stop() {
+	ps.mu.Lock()
+	c := ps.cron
+	ps.cron = nil
+	ps.mu.Unlock()
+
+	if c != nil {
+		<-c.Stop().Done()
+	}
+}
+
+func (ps *peerService) get(endpoint string, data any) error {
+	resp, err := ps.client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, data)
+}
+
+func (ps *peerService) post(endpoint string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ps.client.Post(endpoint, "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, nil)
+}
+
+// decodeResponse reads the {"data": ...} or {"error": ...} body every endpoint answers with.
+func decodeResponse(resp *http.Response, data any) error {
+	var body struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("%s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error == "" {
+			body.Error = resp.Status
+		}
+		return errors.New(body.Error)
+	}
+	if data == nil || len(body.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body.Data, data)
+}
+
+// decodeWork is the total work of a hex chain work, 0 when it is missing.
+func decodeWork(chainWork string) *big.Int {
+	work, err := hexutil.DecodeBig(chainWork)
+	if err != nil {
+		return new(big.Int)
+	}
+	return work
+}
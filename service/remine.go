@@ -0,0 +1,129 @@
+package service
+
+import (
+	"blockchain-backend/util"
+	"context"
+	"fmt"
+	"time"
+)
+
+type RemineEventType string
+
+const (
+	RemineBlockStarted RemineEventType = "block_started"
+	RemineBlockMined   RemineEventType = "block_mined"
+	RemineDone         RemineEventType = "done"
+)
+
+// RemineEvent reports the progress of RemineFrom, Attempts and DurationMs are totals so far on RemineDone.
+type RemineEvent struct {
+	Type        RemineEventType `json:"type"`
+	BlockNumber int64           `json:"block_number,omitempty"`
+	Remaining   int             `json:"remaining"`
+	Difficulty  int64           `json:"difficulty,omitempty"`
+	Nonce       int64           `json:"nonce,omitempty"`
+	Hash        string          `json:"hash,omitempty"`
+	Attempts    int64           `json:"attempts"`
+	DurationMs  int64           `json:"duration_ms"`
+	HashRate    float64         `json:"hash_rate,omitempty"`
+}
+
+// RemineFrom replaces the data of block blockNumber and re-mines it and every block after it up to the tip,
+// since each block commits to the hash of its parent. Nothing is committed when ctx is cancelled or the
+// chain changed while re-mining. Otherwise the re-mined chain is forced as the head although it carries no more
+// work than the old one, so it replaces the block tree like a restore: the old chain is snapshotted and dropped
+// with the side branches, which could take the head back. onEvent is called from the calling goroutine.
+func (bls *blockchainService) RemineFrom(ctx context.Context, blockNumber int64, data string, workers int, onEvent func(RemineEvent)) error {
+	bls.mu.RLock()
+	index := -1
+	for i, block := range bls.chain.Blocks {
+		if block.BlockNumber == blockNumber {
+			index = i
+			break
+		}
+	}
+	if index < 1 {
+		bls.mu.RUnlock()
+		if index == 0 {
+			return fmt.Errorf("the genesis block cannot be re-mined")
+		}
+		return fmt.Errorf("block not found")
+	}
+	original := append([]Block{}, bls.chain.Blocks...)
+	bls.mu.RUnlock()
+	blocks := append([]Block{}, original...)
+
+	engine := bls.blockService.Engine()
+	var totalAttempts int64
+	startedAt := time.Now()
+	for i := index; i < len(blocks); i++ {
+		block := blocks[i]
+		block.ParentHash = blocks[i-1].Hash
+		if i == index {
+			block.Data = data
+		}
+		if interval, _ := bls.blockService.GetRetargetConfig(); interval > 0 || engine.Name() != ProofOfWork {
+			// re-mined timestamps move the retarget windows, and signer turns depend on who re-signs
+			engine.Prepare(blocks[:i], &block)
+		}
+
+		onEvent(RemineEvent{
+			Type:        RemineBlockStarted,
+			BlockNumber: block.BlockNumber,
+			Remaining:   len(blocks) - i,
+			Difficulty:  block.Difficulty,
+			Attempts:    totalAttempts,
+		})
+
+		progress := &MiningProgress{}
+		blockStartedAt := time.Now()
+		if err := engine.Seal(ctx, blocks[:i], &block, MiningOptions{Workers: workers, Progress: progress}); err != nil {
+			return err
+		}
+		block.ChainWork = util.AddWork(blocks[i-1].ChainWork, engine.Work(&block))
+		attempts, _, _, _, hashRate := progress.snapshot()
+		totalAttempts += attempts
+		blocks[i] = block
+
+		onEvent(RemineEvent{
+			Type:        RemineBlockMined,
+			BlockNumber: block.BlockNumber,
+			Remaining:   len(blocks) - i - 1,
+			Difficulty:  block.Difficulty,
+			Nonce:       block.Nonce,
+			Hash:        block.Hash,
+			Attempts:    attempts,
+			DurationMs:  time.Since(blockStartedAt).Milliseconds(),
+			HashRate:    hashRate,
+		})
+	}
+
+	bls.mu.Lock()
+	defer bls.mu.Unlock()
+	if len(bls.chain.Blocks) != len(original) {
+		return fmt.Errorf("chain changed while re-mining")
+	}
+	for i := range original {
+		if bls.chain.Blocks[i].Hash != original[i].Hash {
+			return fmt.Errorf("chain changed while re-mining")
+		}
+	}
+	bls.snapshot(SnapshotRemine)
+	// the re-mined blocks fork off the parent of the edited block, switching to them records the reorg
+	if err := bls.addBranch(blocks); err != nil {
+		return err
+	}
+	bls.setHead(blocks[len(blocks)-1].Hash, ReorgRemine)
+	reorgs := bls.tree.reorgs
+	bls.rebuildTree()
+	bls.tree.reorgs = reorgs
+	bls.rewriteStore()
+
+	onEvent(RemineEvent{
+		Type:       RemineDone,
+		Remaining:  0,
+		Attempts:   totalAttempts,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	})
+	return nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"blockchain-backend/config"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	defaultInitialReward int64 = 10
+	defaultFaucetLimit   int64 = 10000
+)
+
+// RewardSchedule is the issuance of new coins to miners, keyed on block height.
+// The first mined block is height 2 since the genesis block is height 1.
+type RewardSchedule struct {
+	InitialReward   int64 `json:"initial_reward"`
+	HalvingInterval int64 `json:"halving_interval"` // blocks between halvings, 0 never halves
+	MaxSupply       int64 `json:"max_supply"`       // cap on coins issued as block rewards and faucet grants, 0 is uncapped
+	FaucetLimit     int64 `json:"faucet_limit"`     // coins the faucet grants of one block may create
+}
+
+// RewardInfo reports the issuance state of the chain.
+type RewardInfo struct {
+	Schedule          RewardSchedule `json:"schedule"`
+	Height            int64          `json:"height"`
+	CurrentReward     int64          `json:"current_reward"`
+	NextHalvingHeight int64          `json:"next_halving_height"`
+	IssuedRewards     int64          `json:"issued_rewards"`
+	FaucetSupply      int64          `json:"faucet_supply"`
+	CirculatingSupply int64          `json:"circulating_supply"`
+}
+
+func NewRewardSchedule() RewardSchedule {
+	schedule := RewardSchedule{
+		InitialReward:   config.ConfigEnv.InitialReward,
+		HalvingInterval: config.ConfigEnv.HalvingInterval,
+		MaxSupply:       config.ConfigEnv.MaxSupply,
+		FaucetLimit:     config.ConfigEnv.FaucetLimit,
+	}
+	if schedule.InitialReward <= 0 {
+		schedule.InitialReward = defaultInitialReward
+	}
+	if schedule.FaucetLimit <= 0 {
+		schedule.FaucetLimit = defaultFaucetLimit
+	}
+	return schedule
+}
+
+func (rs RewardSchedule) halvings(height int64) int64 {
+	if rs.HalvingInterval <= 0 || height < 2 {
+		return 0
+	}
+	return (height - 2) / rs.HalvingInterval
+}
+
+// RewardAt is the block reward at height, given the coins issued by the blocks before it.
+func (rs RewardSchedule) RewardAt(height, issued int64) int64 {
+	halvings := rs.halvings(height)
+	if halvings >= 63 {
+		return 0
+	}
+
+	reward := rs.InitialReward >> halvings
+	if rs.MaxSupply > 0 {
+		reward = min(reward, max(rs.MaxSupply-issued, 0))
+	}
+	return reward
+}
+
+// FaucetAt is the most coins the faucet grants of a block paying reward may create, given the coins issued by the
+// blocks before it.
+func (rs RewardSchedule) FaucetAt(reward, issued int64) int64 {
+	limit := rs.FaucetLimit
+	if rs.MaxSupply > 0 {
+		limit = min(limit, max(rs.MaxSupply-issued-reward, 0))
+	}
+	return limit
+}
+
+// NextHalvingHeight is the first height after height with a halved reward, 0 when rewards never halve.
+func (rs RewardSchedule) NextHalvingHeight(height int64) int64 {
+	if rs.HalvingInterval <= 0 {
+		return 0
+	}
+	return 2 + (rs.halvings(height)+1)*rs.HalvingInterval
+}
+
+// blockIssuance splits the coins a block creates into the block reward and faucet grants.
+// The coinbase of a block pays reward plus fees, fees only move existing coins so they are taken out.
+func blockIssuance(block Block) (reward, faucet int64) {
+	zeroAddress := common.Address{}.Hex()
+
+	for i, transaction := range block.Transactions {
+		if strings.Compare(transaction.From, zeroAddress) != 0 {
+			continue
+		}
+
+		isCoinbase := i == 0
+		// legacy blocks kept the coinbase at a random position
+		if block.Version == BlockVersionLegacy {
+			isCoinbase = strings.Compare(transaction.To, block.Miner) == 0
+		}
+
+		if isCoinbase && reward == 0 {
+			reward = transaction.Value - totalFees(block.Transactions)
+		} else {
+			faucet += transaction.Value
+		}
+	}
+
+	return reward, faucet
+}
+
+// chainIssuance sums blockIssuance over blocks.
+func chainIssuance(blocks []Block) (rewards, faucet int64) {
+	for _, block := range blocks {
+		blockReward, blockFaucet := blockIssuance(block)
+		rewards += blockReward
+		faucet += blockFaucet
+	}
+	return rewards, faucet
+}
+
+// withinFaucet drops the faucet grants that would take the grants of transactions past limit, older grants go
+// first. Grants of no value are dropped too since blocks may not carry them.
+func withinFaucet(transactions []Transaction, limit int64) []Transaction {
+	zeroAddress := common.Address{}.Hex()
+
+	ordered := append([]Transaction{}, transactions...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return olderThan(ordered[i], ordered[j])
+	})
+
+	kept := make([]Transaction, 0, len(ordered))
+	for _, transaction := range ordered {
+		if strings.Compare(transaction.From, zeroAddress) == 0 {
+			if transaction.Value <= 0 || transaction.Value > limit {
+				continue
+			}
+			limit -= transaction.Value
+		}
+		kept = append(kept, transaction)
+	}
+	return kept
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"blockchain-backend/infras/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sandbox is a chain of its own, with its own pool and difficulty, next to the chain of the node.
+type Sandbox struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+var ErrSandboxNotFound = errors.New("sandbox not found")
+
+var sandboxID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+type ISandboxService interface {
+	CreateSandbox(id string, description string) (Sandbox, error)
+	GetSandboxes() []Sandbox
+	GetSandbox(id string) (Sandbox, error)
+	DeleteSandbox(id string) error
+	// Store is the storage of the sandbox, every key lives under SANDBOX:<id>:
+	Store(id string) storage.IStorage
+	OnCreateSandbox(listener func(id string))
+	OnDeleteSandbox(listener func(id string))
+}
+
+// sandboxService keeps the sandboxes as one JSON object under SandboxesKey. The listeners are called without
+// ss.mu held, so they may look sandboxes up.
+type sandboxService struct {
+	mu              sync.Mutex
+	store           storage.IStorage
+	createListeners []func(id string)
+	deleteListeners []func(id string)
+}
+
+func NewSandboxService(store storage.IStorage) ISandboxService {
+	return &sandboxService{
+		store: store,
+	}
+}
+
+// load must be called with ss.mu held.
+func (ss *sandboxService) load() map[string]Sandbox {
+	sandboxes := map[string]Sandbox{}
+	if value := ss.store.Get(storage.SandboxesKey); value != "" {
+		_ = json.Unmarshal([]byte(value), &sandboxes)
+	}
+	return sandboxes
+}
+
+// save must be called with ss.mu held.
+func (ss *sandboxService) save(sandboxes map[string]Sandbox) {
+	value, _ := json.Marshal(sandboxes)
+	ss.store.Set(storage.SandboxesKey, string(value))
+}
+
+func (ss *sandboxService) CreateSandbox(id string, description string) (Sandbox, error) {
+	if !sandboxID.MatchString(id) {
+		return Sandbox{}, fmt.Errorf("sandbox id must be 1 to 32 lower case letters, digits, - or _")
+	}
+
+	ss.mu.Lock()
+	sandboxes := ss.load()
+	if _, ok := sandboxes[id]; ok {
+		ss.mu.Unlock()
+		return Sandbox{}, fmt.Errorf("sandbox %s already exists", id)
+	}
+
+	sandbox := Sandbox{
+		ID:          id,
+		Description: description,
+		CreatedAt:   time.Now().Unix(),
+	}
+	sandboxes[id] = sandbox
+	ss.save(sandboxes)
+	listeners := ss.createListeners
+	ss.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(id)
+	}
+	return sandbox, nil
+}
+
+func (ss *sandboxService) GetSandboxes() []Sandbox {
+	ss.mu.Lock()
+	sandboxes := ss.load()
+	ss.mu.Unlock()
+
+	list := make([]Sandbox, 0, len(sandboxes))
+	for _, sandbox := range sandboxes {
+		list = append(list, sandbox)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+func (ss *sandboxService) GetSandbox(id string) (Sandbox, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	sandbox, ok := ss.load()[id]
+	if !ok {
+		return Sandbox{}, ErrSandboxNotFound
+	}
+	return sandbox, nil
+}
+
+// DeleteSandbox tells the listeners, so they stop using the sandbox, before its keys are deleted. The sandbox
+// stays registered until then, so it cannot be created again while it is being deleted.
+func (ss *sandboxService) DeleteSandbox(id string) error {
+	if _, err := ss.GetSandbox(id); err != nil {
+		return err
+	}
+
+	ss.mu.Lock()
+	listeners := ss.deleteListeners
+	ss.mu.Unlock()
+	for _, listener := range listeners {
+		listener(id)
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// a concurrent delete may have finished in the meantime
+	sandboxes := ss.load()
+	if _, ok := sandboxes[id]; !ok {
+		return ErrSandboxNotFound
+	}
+
+	store := ss.Store(id)
+	store.Del(store.Keys("")...)
+	delete(sandboxes, id)
+	ss.save(sandboxes)
+	return nil
+}
+
+func (ss *sandboxService) Store(id string) storage.IStorage {
+	return storage.Namespace(ss.store, storage.SandboxKeyPrefix+id+":")
+}
+
+// OnCreateSandbox calls listener with the id of every sandbox that was created.
+func (ss *sandboxService) OnCreateSandbox(listener func(id string)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.createListeners = append(ss.createListeners, listener)
+}
+
+// OnDeleteSandbox calls listener with the id of every sandbox that is about to be deleted.
+func (ss *sandboxService) OnDeleteSandbox(listener func(id string)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.deleteListeners = append(ss.deleteListeners, listener)
+}
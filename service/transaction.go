@@ -10,21 +10,35 @@ import (
 	"time"
 )
 
+type TransactionType string
+
+const (
+	TransferTransaction TransactionType = ""
+	// StakeTransaction locks Value of the sender's balance as proof-of-stake validator stake, From and To are the same
+	StakeTransaction TransactionType = "stake"
+	// UnstakeTransaction unlocks Value of the sender's stake back into the balance
+	UnstakeTransaction TransactionType = "unstake"
+)
+
 type Transaction struct {
-	Hash      string `json:"hash"`
-	Signature string `json:"signature"`
-	From      string `json:"from"`
-	To        string `json:"to"`
-	Value     int64  `json:"value"`
-	Data      string `json:"data"`
-	Timestamp int64  `json:"timestamp"`
+	Hash      string          `json:"hash"`
+	Signature string          `json:"signature"`
+	Type      TransactionType `json:"type,omitempty"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Value     int64           `json:"value"`
+	Fee       int64           `json:"fee"`
+	Data      string          `json:"data"`
+	Timestamp int64           `json:"timestamp"`
 }
 
 type ITransactionService interface {
 	ValidTransaction(transaction *Transaction, pubKey string) bool
+	VerifyTransaction(transaction *Transaction) error
 	TxHash(transaction *Transaction) string
-	RewardTransaction(miner string) *Transaction
-	CreateTransaction(from string, to string, value int64, data string, timestamp int64, signature string, pubKey string) (*Transaction, error)
+	LegacyTxHash(transaction *Transaction) string
+	RewardTransaction(miner string, amount int64) *Transaction
+	CreateTransaction(txType TransactionType, from string, to string, value int64, fee int64, data string, timestamp int64, signature string, pubKey string) (*Transaction, error)
 }
 
 type transactionService struct {
@@ -34,7 +48,49 @@ func NewTransactionService() ITransactionService {
 	return &transactionService{}
 }
 
+// TxHash hashes the canonical encoding of the signed transaction fields. The type is only encoded for
+// staking transactions so transfers keep the hash they had before transaction types existed.
 func (ts *transactionService) TxHash(transaction *Transaction) string {
+	encoder := util.NewEncoder("blab/transaction").
+		String(transaction.From).
+		String(transaction.To).
+		Int64(transaction.Value).
+		Int64(transaction.Fee).
+		String(transaction.Data).
+		Int64(transaction.Timestamp)
+	if transaction.Type != TransferTransaction {
+		encoder.String(string(transaction.Type))
+	}
+
+	return util.CryptoHash(encoder.Bytes()).Hex()
+}
+
+// encodeTransaction is the canonical encoding of the whole transaction, including hash and signature.
+func encodeTransaction(transaction *Transaction) []byte {
+	encoder := util.NewEncoder("blab/transaction").
+		String(transaction.Hash).
+		String(transaction.Signature).
+		String(transaction.From).
+		String(transaction.To).
+		Int64(transaction.Value).
+		Int64(transaction.Fee).
+		String(transaction.Data).
+		Int64(transaction.Timestamp)
+	if transaction.Type != TransferTransaction {
+		encoder.String(string(transaction.Type))
+	}
+
+	return encoder.Bytes()
+}
+
+// EncodedSize is the number of bytes the transaction takes in a block.
+func EncodedSize(transaction *Transaction) int {
+	return len(encodeTransaction(transaction))
+}
+
+// LegacyTxHash is the string concatenation hash used by transactions in BlockVersionLegacy blocks,
+// those predate fees so Fee is not part of it.
+func (ts *transactionService) LegacyTxHash(transaction *Transaction) string {
 	return util.CryptoHash([]byte(transaction.From + transaction.To + strconv.FormatInt(transaction.Value, 10) + transaction.Data + strconv.FormatInt(transaction.Timestamp, 10))).Hex()
 }
 
@@ -44,47 +100,109 @@ func (ts *transactionService) ValidTransaction(transaction *Transaction, pubKey
 		return true
 	}
 
-	if transaction.From == "" {
+	if err := checkTransactionFields(transaction); err != nil {
 		return false
 	}
 
-	if transaction.To == "" {
+	hashBytes, err := hexutil.Decode(transaction.Hash)
+	if err != nil {
 		return false
 	}
 
-	if transaction.Value <= 0 {
+	if !util.VerifySignature(
+		pubKey,
+		hashBytes,
+		transaction.Signature,
+	) {
 		return false
 	}
 
-	if transaction.Data == "" {
+	// the public key must belong to the sender
+	if signer, err := util.RecoverAddress(hashBytes, transaction.Signature); err != nil || !strings.EqualFold(signer, transaction.From) {
 		return false
 	}
 
-	if transaction.Timestamp <= 0 {
-		return false
+	return true
+}
+
+// VerifyTransaction checks a transaction read from a block, which carries no public key: the fields must be
+// valid, Hash must be its TxHash and the signature must recover to From. Transactions from the zero address
+// are coinbases and faucet grants and are not signed.
+func (ts *transactionService) VerifyTransaction(transaction *Transaction) error {
+	if strings.Compare(transaction.From, common.Address{}.Hex()) == 0 {
+		return nil
+	}
+
+	if err := checkTransactionFields(transaction); err != nil {
+		return newRuleError(ErrInvalidTransaction, "", "", "%s", err)
+	}
+
+	if expected := ts.TxHash(transaction); transaction.Hash != expected {
+		return newRuleError(ErrInvalidHash, expected, transaction.Hash, "invalid transaction hash")
 	}
 
 	hashBytes, err := hexutil.Decode(transaction.Hash)
 	if err != nil {
-		return false
+		return newRuleError(ErrInvalidHash, "", transaction.Hash, "%s", err)
 	}
 
-	if !util.VerifySignature(
-		pubKey,
-		hashBytes,
-		transaction.Signature,
-	) {
-		return false
+	signer, err := util.RecoverAddress(hashBytes, transaction.Signature)
+	if err != nil {
+		return newRuleError(ErrInvalidSignature, "", transaction.Signature, "invalid signature: %s", err)
+	}
+	if !strings.EqualFold(signer, transaction.From) {
+		return newRuleError(ErrInvalidSignature, transaction.From, signer, "signed by %s instead of the sender", signer)
 	}
 
-	return true
+	return nil
+}
+
+// checkTransactionFields checks the fields of a signed transaction.
+func checkTransactionFields(transaction *Transaction) error {
+	if transaction.From == "" {
+		return fmt.Errorf("sender is missing")
+	}
+
+	switch transaction.Type {
+	case TransferTransaction:
+	case StakeTransaction, UnstakeTransaction:
+		// stake stays with the sender
+		if strings.Compare(transaction.From, transaction.To) != 0 {
+			return fmt.Errorf("%s transaction must be sent to the sender", transaction.Type)
+		}
+	default:
+		return fmt.Errorf("unknown transaction type %s", transaction.Type)
+	}
+
+	if transaction.To == "" {
+		return fmt.Errorf("recipient is missing")
+	}
+
+	if transaction.Value <= 0 {
+		return fmt.Errorf("value must be positive")
+	}
+
+	if transaction.Fee < 0 {
+		return fmt.Errorf("fee must not be negative")
+	}
+
+	if transaction.Data == "" {
+		return fmt.Errorf("data is missing")
+	}
+
+	if transaction.Timestamp <= 0 {
+		return fmt.Errorf("timestamp is missing")
+	}
+
+	return nil
 }
 
-func (ts *transactionService) RewardTransaction(miner string) *Transaction {
+// RewardTransaction is the coinbase of a block, amount is the block reward plus the fees of the block.
+func (ts *transactionService) RewardTransaction(miner string, amount int64) *Transaction {
 	transaction := &Transaction{
 		From:      common.Address{}.Hex(),
 		To:        miner,
-		Value:     util.MinersReward,
+		Value:     amount,
 		Data:      "",
 		Timestamp: time.Now().Unix(),
 	}
@@ -94,11 +212,13 @@ func (ts *transactionService) RewardTransaction(miner string) *Transaction {
 	return transaction
 }
 
-func (ts *transactionService) CreateTransaction(from string, to string, value int64, data string, timestamp int64, signature string, pubKey string) (*Transaction, error) {
+func (ts *transactionService) CreateTransaction(txType TransactionType, from string, to string, value int64, fee int64, data string, timestamp int64, signature string, pubKey string) (*Transaction, error) {
 	transaction := &Transaction{
+		Type:      txType,
 		From:      from,
 		To:        to,
 		Value:     value,
+		Fee:       fee,
 		Data:      data,
 		Timestamp: timestamp,
 		Signature: signature,
@@ -109,9 +229,5 @@ func (ts *transactionService) CreateTransaction(from string, to string, value in
 		return nil, fmt.Errorf("invalid transaction")
 	}
 
-	//transactionBytes, _ := json.Marshal(transaction)
-	//log.Println("Publishing transaction to redis", transaction)
-	//redis.RedisService.Publish(redis.ChannelSyncTransactionKey, string(transactionBytes))
-
 	return transaction, nil
 }
@@ -1,9 +1,10 @@
 package service
 
 import (
-	redisPkg "blockchain-backend/infras/redis"
+	"blockchain-backend/infras/storage"
 	"encoding/json"
 	"log"
+	"sync"
 )
 
 type TxPoolConfigSource string
@@ -15,23 +16,30 @@ const (
 
 type ITransactionPoolService interface {
 	Clear()
+	Replace(transactions []Transaction)
+	Remove(hashes ...string)
 	SetTransaction(transaction *Transaction)
 	GetTransactionPool() map[string]Transaction
 	GetTransactions() []Transaction
 	ConfigTransactionPool(sourceType TxPoolConfigSource)
 	GetConfigTransactionPool() TxPoolConfigSource
+	OnNewTransaction(listener func(transaction Transaction))
 }
 
 type transactionPoolService struct {
+	mu                 sync.RWMutex
 	sourceType         TxPoolConfigSource
 	transactionMap     map[string]Transaction
 	transactionService ITransactionService
+	store              storage.IStorage
+	listeners          []func(transaction Transaction)
 }
 
-func NewTransactionPoolService(transactionService ITransactionService) ITransactionPoolService {
+func NewTransactionPoolService(transactionService ITransactionService, store storage.IStorage) ITransactionPoolService {
 	return &transactionPoolService{
 		transactionMap:     make(map[string]Transaction),
 		transactionService: transactionService,
+		store:              store,
 	}
 }
 
@@ -50,10 +58,29 @@ func (tps *transactionPoolService) ConfigTransactionPool(sourceType TxPoolConfig
 }
 
 func (tps *transactionPoolService) Clear() {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+
 	tps.transactionMap = make(map[string]Transaction)
+	tps.persist()
+}
+
+// Replace swaps the pool for transactions without calling the listeners, for a pool this node had before so
+// nothing is announced again.
+func (tps *transactionPoolService) Replace(transactions []Transaction) {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+
+	tps.transactionMap = make(map[string]Transaction, len(transactions))
+	for _, transaction := range transactions {
+		tps.transactionMap[transaction.Hash] = transaction
+	}
+	tps.persist()
 }
 
 func (tps *transactionPoolService) SetTransaction(transaction *Transaction) {
+	tps.mu.Lock()
+
 	isExist := false
 	if _, ok := tps.transactionMap[transaction.Hash]; ok {
 		isExist = true
@@ -62,18 +89,49 @@ func (tps *transactionPoolService) SetTransaction(transaction *Transaction) {
 	if !isExist {
 		tps.transactionMap[transaction.Hash] = *transaction
 	}
+	tps.persist()
+	listeners := tps.listeners
+	tps.mu.Unlock()
+
+	if !isExist {
+		for _, listener := range listeners {
+			listener(*transaction)
+		}
+	}
+}
+
+// OnNewTransaction calls listener with every transaction that enters the pool.
+func (tps *transactionPoolService) OnNewTransaction(listener func(transaction Transaction)) {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+	tps.listeners = append(tps.listeners, listener)
+}
+
+// Remove drops the given transactions from the pool, unknown hashes are ignored.
+func (tps *transactionPoolService) Remove(hashes ...string) {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+
+	for _, hash := range hashes {
+		delete(tps.transactionMap, hash)
+	}
+	tps.persist()
+}
+
+// persist must be called with tps.mu held.
+func (tps *transactionPoolService) persist() {
 	transactions := make([]Transaction, 0)
 	for _, tx := range tps.transactionMap {
 		transactions = append(transactions, tx)
 	}
 	tsxBytes, _ := json.Marshal(transactions)
 
-	redisPkg.RedisService.Set(redisPkg.TransactionPoolKey, string(tsxBytes))
+	tps.store.Set(storage.TransactionPoolKey, string(tsxBytes))
 }
 
 func (tps *transactionPoolService) GetTransactionPool() map[string]Transaction {
 	if tps.sourceType == Redis {
-		txPool := redisPkg.RedisService.Get(redisPkg.TransactionPoolKey)
+		txPool := tps.store.Get(storage.TransactionPoolKey)
 		var txs []Transaction
 		if txPool != "" {
 			err := json.Unmarshal([]byte(txPool), &txs)
@@ -82,7 +140,15 @@ func (tps *transactionPoolService) GetTransactionPool() map[string]Transaction {
 			}
 		}
 	}
-	return tps.transactionMap
+
+	tps.mu.RLock()
+	defer tps.mu.RUnlock()
+
+	transactionMap := make(map[string]Transaction, len(tps.transactionMap))
+	for hash, transaction := range tps.transactionMap {
+		transactionMap[hash] = transaction
+	}
+	return transactionMap
 }
 
 func (tps *transactionPoolService) GetTransactions() []Transaction {
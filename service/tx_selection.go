@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+)
+
+type TxSelectionStrategy string
+
+const (
+	// OldestFirst fills the block with the earliest transactions
+	OldestFirst TxSelectionStrategy = "oldest"
+	// HighestFee fills the block with the transactions paying the most to the miner
+	HighestFee TxSelectionStrategy = "fee"
+	// SenderFairness takes one transaction per sender in turn so a busy sender cannot fill the block
+	SenderFairness TxSelectionStrategy = "fair"
+)
+
+// BlockPolicy decides which pool transactions go into the next block, limits of 0 are unlimited.
+type BlockPolicy struct {
+	MaxTransactions int                 `json:"max_transactions"`
+	MaxBytes        int                 `json:"max_bytes"`
+	Strategy        TxSelectionStrategy `json:"strategy"`
+}
+
+func (bp BlockPolicy) Validate() error {
+	if bp.MaxTransactions < 0 || bp.MaxBytes < 0 {
+		return fmt.Errorf("block limits must not be negative")
+	}
+
+	switch bp.Strategy {
+	case OldestFirst, HighestFee, SenderFairness:
+		return nil
+	default:
+		return fmt.Errorf("strategy must be one of %s, %s, %s", OldestFirst, HighestFee, SenderFairness)
+	}
+}
+
+// Select orders candidates by the strategy and takes as many as fit next to the reserved transactions,
+// a transaction too large for the remaining space is skipped so smaller ones behind it can still fit.
+func (bp BlockPolicy) Select(candidates []Transaction, reserved ...Transaction) []Transaction {
+	count, size := len(reserved), 0
+	for i := range reserved {
+		size += EncodedSize(&reserved[i])
+	}
+
+	selected := make([]Transaction, 0)
+	for _, transaction := range bp.order(candidates) {
+		if bp.MaxTransactions > 0 && count >= bp.MaxTransactions {
+			break
+		}
+
+		transactionSize := EncodedSize(&transaction)
+		if bp.MaxBytes > 0 && size+transactionSize > bp.MaxBytes {
+			continue
+		}
+
+		selected = append(selected, transaction)
+		count++
+		size += transactionSize
+	}
+
+	return selected
+}
+
+func (bp BlockPolicy) order(candidates []Transaction) []Transaction {
+	ordered := append([]Transaction{}, candidates...)
+	sort.Slice(ordered, func(i, j int) bool {
+		if bp.Strategy == HighestFee && ordered[i].Fee != ordered[j].Fee {
+			return ordered[i].Fee > ordered[j].Fee
+		}
+		return olderThan(ordered[i], ordered[j])
+	})
+
+	if bp.Strategy != SenderFairness {
+		return ordered
+	}
+
+	// round robin over senders, senders take turns in the order of their oldest transaction
+	var senders []string
+	bySender := make(map[string][]Transaction)
+	for _, transaction := range ordered {
+		if _, ok := bySender[transaction.From]; !ok {
+			senders = append(senders, transaction.From)
+		}
+		bySender[transaction.From] = append(bySender[transaction.From], transaction)
+	}
+
+	fair := make([]Transaction, 0, len(ordered))
+	for len(fair) < len(ordered) {
+		for _, sender := range senders {
+			if queue := bySender[sender]; len(queue) > 0 {
+				fair = append(fair, queue[0])
+				bySender[sender] = queue[1:]
+			}
+		}
+	}
+
+	return fair
+}
+
+func olderThan(a, b Transaction) bool {
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp < b.Timestamp
+	}
+	return a.Hash < b.Hash
+}
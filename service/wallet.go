@@ -9,6 +9,7 @@ type IWalletService interface {
 	GenerateKeyPair(seedPhrase string) (util.KeyPair, error)
 	SignTransaction(tx Transaction, privateKey string) (string, error)
 	CalculateBalance(address string) int64
+	CalculateStake(address string) int64
 	CalculateAllBalances() map[string]int64
 }
 
@@ -37,11 +38,16 @@ func (ws *walletService) CalculateAllBalances() map[string]int64 {
 				balances[transaction.To] = 0
 			}
 
-			balances[transaction.From] -= transaction.Value
+			balances[transaction.From] -= transaction.Value + transaction.Fee
 			balances[transaction.To] += transaction.Value
 		}
 	}
 
+	// staked coins are locked
+	for address, stake := range chainStakes(chain.Blocks) {
+		balances[address] -= stake
+	}
+
 	// remove address 0x0000000000000000000000000000000000000000
 	delete(balances, "0x0000000000000000000000000000000000000000")
 
@@ -61,6 +67,7 @@ func (ws *walletService) SignTransaction(tx Transaction, privateKey string) (str
 	return util.Sign(data, privateKey)
 }
 
+// CalculateBalance is the spendable balance of address, stake locked by the address is not included.
 func (ws *walletService) CalculateBalance(address string) int64 {
 	var balance int64 = 0
 	chain := ws.blockChainSvc.GetBlocks()
@@ -68,7 +75,7 @@ func (ws *walletService) CalculateBalance(address string) int64 {
 	for _, block := range chain.Blocks {
 		for _, transaction := range block.Transactions {
 			if transaction.From == address {
-				balance -= transaction.Value
+				balance -= transaction.Value + transaction.Fee
 			}
 
 			if transaction.To == address {
@@ -78,5 +85,9 @@ func (ws *walletService) CalculateBalance(address string) int64 {
 
 	}
 
-	return balance
+	return balance - chainStakes(chain.Blocks)[address]
+}
+
+func (ws *walletService) CalculateStake(address string) int64 {
+	return chainStakes(ws.blockChainSvc.GetBlocks().Blocks)[address]
 }
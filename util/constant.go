@@ -1,5 +0,0 @@
-package util
-
-var (
-	MinersReward int64 = 10
-)
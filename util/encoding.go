@@ -0,0 +1,87 @@
+package util
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Encoder builds canonical preimages for hashing. Integers are fixed width big-endian and every
+// string is prefixed with its length, so no two different field values encode to the same bytes.
+type Encoder struct {
+	buf []byte
+}
+
+// NewEncoder starts a preimage with a domain tag so different structures never share a preimage.
+func NewEncoder(domain string) *Encoder {
+	return (&Encoder{}).String(domain)
+}
+
+func (e *Encoder) Uint64(v uint64) *Encoder {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, v)
+	return e
+}
+
+func (e *Encoder) Int64(v int64) *Encoder {
+	return e.Uint64(uint64(v))
+}
+
+func (e *Encoder) String(v string) *Encoder {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(v)))
+	e.buf = append(e.buf, v...)
+	return e
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+var ErrShortBuffer = errors.New("encoded value is truncated")
+
+// Decoder reads back what an Encoder wrote. The first truncated value sets Err and every later read
+// returns the zero value, so a caller checks Err once after reading all fields.
+type Decoder struct {
+	buf []byte
+	err error
+}
+
+func NewDecoder(buf []byte) *Decoder {
+	return &Decoder{buf: buf}
+}
+
+func (d *Decoder) Uint64() uint64 {
+	if d.err != nil || len(d.buf) < 8 {
+		d.err = ErrShortBuffer
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *Decoder) Int64() int64 {
+	return int64(d.Uint64())
+}
+
+func (d *Decoder) String() string {
+	if d.err != nil || len(d.buf) < 4 {
+		d.err = ErrShortBuffer
+		return ""
+	}
+	n := binary.BigEndian.Uint32(d.buf)
+	if uint64(len(d.buf)-4) < uint64(n) {
+		d.err = ErrShortBuffer
+		return ""
+	}
+	v := string(d.buf[4 : 4+n])
+	d.buf = d.buf[4+n:]
+	return v
+}
+
+// Len is the number of bytes left to read.
+func (d *Decoder) Len() int {
+	return len(d.buf)
+}
+
+func (d *Decoder) Err() error {
+	return d.err
+}
@@ -70,6 +70,21 @@ func VerifySignature(publicKey string, data []byte, signature string) bool {
 	return crypto.VerifySignature(publicKeyBytes, data, signatureBytes)
 }
 
+// RecoverAddress returns the address of the key that produced signature over data.
+func RecoverAddress(data []byte, signature string) (string, error) {
+	signatureBytes, err := hexutil.Decode(signature)
+	if err != nil {
+		return "", err
+	}
+
+	publicKey, err := crypto.SigToPub(data, signatureBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return crypto.PubkeyToAddress(*publicKey).Hex(), nil
+}
+
 func HexToBin(hexString string) (string, error) {
 	if strings.HasPrefix(hexString, "0x") {
 		hexString = hexString[2:]
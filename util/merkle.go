@@ -0,0 +1,100 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type MerklePosition string
+
+const (
+	MerkleLeft  MerklePosition = "left"
+	MerkleRight MerklePosition = "right"
+)
+
+// MerkleProofStep is a sibling hash and the side it is concatenated on when walking up to the root.
+type MerkleProofStep struct {
+	Hash     string         `json:"hash"`
+	Position MerklePosition `json:"position"`
+}
+
+func merkleParent(left, right common.Hash) common.Hash {
+	return CryptoHash(append(left.Bytes(), right.Bytes()...))
+}
+
+// merkleLevels returns every level of the tree, leaves first. An odd node is paired with itself like in Bitcoin,
+// so leaves must be unique or a repeated tail gives the same root.
+func merkleLevels(leaves []string) [][]common.Hash {
+	level := make([]common.Hash, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = common.HexToHash(leaf)
+	}
+
+	levels := [][]common.Hash{level}
+	for len(level) > 1 {
+		next := make([]common.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			next = append(next, merkleParent(level[i], right))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return levels
+}
+
+// MerkleRoot computes the Keccak256 Merkle root of hex encoded leaf hashes, the root of no leaves is the zero hash.
+func MerkleRoot(leaves []string) string {
+	if len(leaves) == 0 {
+		return common.Hash{}.Hex()
+	}
+
+	levels := merkleLevels(leaves)
+	return levels[len(levels)-1][0].Hex()
+}
+
+// MerkleProof returns the sibling path from the leaf at index up to the root.
+func MerkleProof(leaves []string, index int) ([]MerkleProofStep, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range", index)
+	}
+
+	levels := merkleLevels(leaves)
+	proof := make([]MerkleProofStep, 0, len(levels)-1)
+	for _, level := range levels[:len(levels)-1] {
+		if index%2 == 0 {
+			sibling := level[index]
+			if index+1 < len(level) {
+				sibling = level[index+1]
+			}
+			proof = append(proof, MerkleProofStep{Hash: sibling.Hex(), Position: MerkleRight})
+		} else {
+			proof = append(proof, MerkleProofStep{Hash: level[index-1].Hex(), Position: MerkleLeft})
+		}
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof folds the proof over leaf and checks the result against root.
+func VerifyMerkleProof(leaf string, proof []MerkleProofStep, root string) bool {
+	hash := common.HexToHash(leaf)
+	for _, step := range proof {
+		switch step.Position {
+		case MerkleLeft:
+			hash = merkleParent(common.HexToHash(step.Hash), hash)
+		case MerkleRight:
+			hash = merkleParent(hash, common.HexToHash(step.Hash))
+		default:
+			return false
+		}
+	}
+
+	return hash == common.HexToHash(root)
+}
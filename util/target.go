@@ -0,0 +1,95 @@
+package util
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// oneLsh256 is 2^256, one more than the largest hash
+var oneLsh256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// CompactToBig decodes a Bitcoin style compact target: the high byte is the length of the target in bytes
+// and the low 3 bytes are its most significant bytes. Targets are never negative so the sign bit is ignored.
+func CompactToBig(compact uint32) *big.Int {
+	mantissa := int64(compact & 0x007fffff)
+	exponent := uint(compact >> 24)
+
+	if exponent <= 3 {
+		return big.NewInt(mantissa >> (8 * (3 - exponent)))
+	}
+	return new(big.Int).Lsh(big.NewInt(mantissa), 8*(exponent-3))
+}
+
+// BigToCompact encodes target as a compact target, rounding down to 3 significant bytes.
+func BigToCompact(target *big.Int) uint32 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+
+	exponent := uint(len(target.Bytes()))
+	var mantissa uint32
+	if exponent <= 3 {
+		mantissa = uint32(target.Uint64()) << (8 * (3 - exponent))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, 8*(exponent-3)).Uint64())
+	}
+
+	// the 0x00800000 bit is the sign, move the mantissa a byte down instead of setting it
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+	return uint32(exponent<<24) | mantissa
+}
+
+// DifficultyToTarget is the target of a difficulty counted in leading zero bits, a hash meets it exactly
+// when it starts with difficulty zero bits.
+func DifficultyToTarget(difficulty int64) *big.Int {
+	difficulty = min(max(difficulty, 0), 256)
+	target := new(big.Int).Lsh(big.NewInt(1), uint(256-difficulty))
+	return target.Sub(target, big.NewInt(1))
+}
+
+// TargetDifficulty is the difficulty of target in leading zero bits, fractional between powers of two.
+func TargetDifficulty(target *big.Int) float64 {
+	work, _ := new(big.Float).SetInt(CalcWork(target)).Float64()
+	return math.Log2(work)
+}
+
+// CalcWork is the expected number of hashes needed to meet target, 2^256 / (target + 1).
+func CalcWork(target *big.Int) *big.Int {
+	if target.Sign() < 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(oneLsh256, new(big.Int).Add(target, big.NewInt(1)))
+}
+
+// MeetsTarget reports whether hash, read as a big-endian number, is at most target.
+func MeetsTarget(hash common.Hash, target *big.Int) bool {
+	return new(big.Int).SetBytes(hash[:]).Cmp(target) <= 0
+}
+
+// LeadingZeroBits counts the zero bits at the start of hash.
+func LeadingZeroBits(hash common.Hash) int {
+	zeros := 0
+	for _, b := range hash {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+// AddWork adds work to the hex encoded chainWork, an empty chainWork counts as zero.
+func AddWork(chainWork string, work *big.Int) string {
+	total, err := hexutil.DecodeBig(chainWork)
+	if err != nil {
+		total = new(big.Int)
+	}
+	return hexutil.EncodeBig(total.Add(total, work))
+}